@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hydrocode-de/gotap/internal/batch"
+	"github.com/hydrocode-de/gotap/internal/config"
+	"github.com/hydrocode-de/gotap/internal/input"
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"github.com/spf13/cobra"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch [toolname]",
+	Short: "Run the tool for many parameter combinations",
+	Long: `Run the tool once for every row of a parameter sweep.
+
+The rows are either the cartesian product of a sweep file, or read from a
+CSV or JSONL file. A sweep file lists the values or a range per parameter:
+
+  parameters:
+    foo_int: [1, 2, 3]
+    foo_float: {start: 0, stop: 1, step: 0.25}
+
+Every row is merged into the inputs.json, validated and run in its own
+folder below --out. Finally, a summary.csv of all runs is written.
+
+Like with serve, the inputs.json and output folder of each row are
+mounted where the tool expects them. This needs mount namespaces (root,
+or CAP_SYS_ADMIN in a container).`,
+	Run: runBatch,
+}
+
+func runBatch(cmd *cobra.Command, args []string) {
+	v := config.GetViper()
+	v.BindPFlag("fail_on_warnings", cmd.Flags().Lookup("fail-on-warnings"))
+	sweepFile, _ := cmd.Flags().GetString("sweep")
+	rowsFile, _ := cmd.Flags().GetString("rows")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	outFolder, _ := cmd.Flags().GetString("out")
+	if outFolder == "" {
+		// next to the output folder, as the runs mount their own view of it
+		outputFolder, err := filepath.Abs(v.GetString("output_folder"))
+		cobra.CheckErr(err)
+		outFolder = filepath.Join(filepath.Dir(outputFolder), "batch")
+	}
+
+	if (sweepFile == "") == (rowsFile == "") {
+		cobra.CheckErr(fmt.Errorf("either --sweep or --rows is required"))
+	}
+
+	spec, err := validation.LoadSpec(args)
+	cobra.CheckErr(err)
+
+	var rows []batch.Row
+	if sweepFile != "" {
+		rows, err = batch.ReadSweep(sweepFile)
+	} else {
+		rows, err = batch.ReadRows(rowsFile, spec)
+	}
+	cobra.CheckErr(err)
+	if len(rows) == 0 {
+		cobra.CheckErr(fmt.Errorf("the sweep has no rows"))
+	}
+
+	// the inputs.json is optional, as the rows may set all inputs
+	files := validation.ConfiguredFiles()
+	var base toolspec.ToolInput
+	if inputs, err := io.ReadInputFile(files.Input); err == nil {
+		base = inputs[spec.Name]
+	}
+
+	sandbox, err := input.NewSandbox(files.Input, v.GetString("output_folder"))
+	cobra.CheckErr(err)
+	cobra.CheckErr(os.MkdirAll(outFolder, 0755))
+
+	results := batch.Run(rows, batch.Options{
+		Files:            files,
+		Spec:             spec,
+		Base:             base,
+		OutputFolder:     outFolder,
+		Parallel:         parallel,
+		FailOnWarnings:   v.GetBool("fail_on_warnings"),
+		ExecutionOptions: executionOptions,
+		Sandbox:          sandbox,
+	})
+
+	records := batch.SummaryRecords(results, batch.Columns(rows))
+	summary, err := os.Create(filepath.Join(outFolder, "summary.csv"))
+	cobra.CheckErr(err)
+	defer summary.Close()
+	cobra.CheckErr(batch.WriteSummary(summary, records))
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, record := range records {
+		fmt.Fprintln(table, strings.Join(record, "\t"))
+	}
+	table.Flush()
+
+	for _, result := range results {
+		if result.Status != batch.StatusSucceeded {
+			summary.Close()
+			os.Exit(1)
+		}
+	}
+}
+
+func init() {
+	batchCmd.Flags().String("sweep", "", "Sweep file with values or ranges per parameter")
+	batchCmd.Flags().String("rows", "", "CSV or JSONL file with one run per row")
+	batchCmd.Flags().Int("parallel", 1, "Number of runs at the same time; needs mount namespaces")
+	batchCmd.Flags().String("out", "", "Folder for the runs and the summary; defaults to batch next to the output folder")
+	batchCmd.Flags().Bool("fail-on-warnings", false, "Skip rows with warnings.")
+	rootCmd.AddCommand(batchCmd)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/cache"
+	"github.com/hydrocode-de/gotap/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the result cache",
+	Long: `Manage the result cache used by run --cache-dir.
+
+Every entry holds the outputs of a successful run. It is keyed by the
+SHA-256 of the tool's entry in tool.yml, the tool version, the image
+digest or docker image, the parameters and the content of every dataset.
+The code of the tool itself is not part of the key, so bump the version
+or the image after changing it.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		v := config.GetViper()
+		v.BindPFlag("cache_dir", cmd.Flags().Lookup("cache-dir"))
+		if v.GetString("cache_dir") == "" {
+			return fmt.Errorf("the cache folder is not set. Use --cache-dir or TAP_CACHE_DIR")
+		}
+		return nil
+	},
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the cache entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := cache.List(config.GetViper().GetString("cache_dir"))
+		cobra.CheckErr(err)
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "KEY\tTOOL\tSIZE\tCREATED\tLAST USED")
+		for _, entry := range entries {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", entry.Key[:12], entry.Tool, entry.Size,
+				entry.Created.Format(time.RFC3339), entry.LastUsed.Format(time.RFC3339))
+		}
+		table.Flush()
+	},
+}
+
+var cacheGcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove old entries from the cache",
+	Long: `Remove entries not used for longer than --max-age. Then, the least
+recently used entries are removed until the cache is smaller than
+--max-size.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		maxAgeValue, _ := cmd.Flags().GetString("max-age")
+		maxAge, err := config.ParseDuration(maxAgeValue)
+		cobra.CheckErr(err)
+		maxSizeValue, _ := cmd.Flags().GetString("max-size")
+		maxSize, err := config.ParseSize(maxSizeValue)
+		cobra.CheckErr(err)
+
+		removed, err := cache.GC(config.GetViper().GetString("cache_dir"), maxAge, int64(maxSize))
+		for _, entry := range removed {
+			fmt.Println("removed", entry.Key)
+		}
+		cobra.CheckErr(err)
+	},
+}
+
+var cacheRmCmd = &cobra.Command{
+	Use:   "rm [key...]",
+	Short: "Remove entries from the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := config.GetViper().GetString("cache_dir")
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			entries, err := cache.List(dir)
+			cobra.CheckErr(err)
+			for _, entry := range entries {
+				cobra.CheckErr(cache.Remove(entry))
+				fmt.Println("removed", entry.Key)
+			}
+			return
+		}
+
+		for _, key := range args {
+			entry, ok, err := cache.Lookup(dir, key)
+			cobra.CheckErr(err)
+			if !ok {
+				cobra.CheckErr(fmt.Errorf("no cache entry with key %s", key))
+			}
+			cobra.CheckErr(cache.Remove(entry))
+			fmt.Println("removed", entry.Key)
+		}
+	},
+}
+
+func init() {
+	cacheCmd.PersistentFlags().String("cache-dir", "", "Folder of the result cache")
+	cacheGcCmd.Flags().String("max-age", "", "Remove entries not used within this duration, e.g. 30d")
+	cacheGcCmd.Flags().String("max-size", "", "Maximum size of the cache, e.g. 10G")
+	cacheRmCmd.Flags().Bool("all", false, "Remove all entries")
+
+	cacheCmd.AddCommand(cacheLsCmd, cacheGcCmd, cacheRmCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
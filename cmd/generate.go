@@ -5,11 +5,14 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hydrocode-de/gotap/internal/config"
 	"github.com/hydrocode-de/gotap/internal/io"
 	"github.com/hydrocode-de/gotap/internal/metadata"
-	"github.com/hydrocode-de/gotap/internal/metadata/converters"
+	_ "github.com/hydrocode-de/gotap/internal/metadata/converters"
 	"github.com/hydrocode-de/gotap/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -18,16 +21,49 @@ import (
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate metadata for this tool",
-	Long:  ``,
-	Run:   generate,
+	Long: `Generate metadata for this tool.
+
+Several formats can be generated at once, by passing a comma separated
+list to --format. Then, --out is required and each format is written to
+its own file in that folder. Use --list-formats to see all formats.`,
+	Run: generate,
 }
 
 func generate(cmd *cobra.Command, args []string) {
 	v := config.GetViper()
+	v.BindPFlag("format", cmd.Flags().Lookup("format"))
+	v.BindPFlag("out", cmd.Flags().Lookup("out"))
+	v.BindPFlag("serialization", cmd.Flags().Lookup("serialization"))
+	v.BindPFlag("docker_image", cmd.Flags().Lookup("docker-image"))
+
+	if list, _ := cmd.Flags().GetBool("list-formats"); list {
+		for _, format := range metadata.Formats() {
+			fmt.Printf("%-16s %-8s %s\n", format.Names[0], format.Extension, strings.Join(format.Names[1:], ", "))
+		}
+		return
+	}
+
 	citationFile := v.GetString("citation_file")
-	format := v.GetString("format")
-	if format == "" {
-		format = "schema.org"
+	outFolder := v.GetString("out")
+	serialization := v.GetString("serialization")
+
+	var formats []metadata.Format
+	for _, name := range strings.Split(v.GetString("format"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		format, err := metadata.Lookup(name)
+		cobra.CheckErr(err)
+		formats = append(formats, format)
+	}
+	if len(formats) == 0 {
+		format, err := metadata.Lookup("schema.org")
+		cobra.CheckErr(err)
+		formats = append(formats, format)
+	}
+	if len(formats) > 1 && outFolder == "" {
+		cobra.CheckErr(fmt.Errorf("generating several formats requires --out"))
 	}
 
 	spec, err := validation.LoadSpec(args)
@@ -37,22 +73,49 @@ func generate(cmd *cobra.Command, args []string) {
 	if err == nil {
 		spec.Citation = citation
 	}
+	license, licenseErr := io.ReadLicenseFile(v.GetString("license_file"))
+	extras, err := io.ReadToolExtras(v.GetString("spec_file"), spec.Name)
+	cobra.CheckErr(err)
 
-	var converter metadata.Converter
-	switch format {
-	case "schema.org":
-		converter = &converters.SchemaOrgConverter{}
-	}
+	for _, format := range formats {
+		converter := format.New()
+		converter.Ingest(spec)
+		if ingester, ok := converter.(metadata.LicenseIngester); ok && licenseErr == nil {
+			ingester.IngestLicense(license)
+		}
+		if ingester, ok := converter.(metadata.ImageIngester); ok {
+			ingester.IngestImage(v.GetString("docker_image"))
+		}
+		if ingester, ok := converter.(metadata.VersionIngester); ok {
+			ingester.IngestVersion(extras.Version)
+		}
+		if ingester, ok := converter.(metadata.OutputsIngester); ok {
+			ingester.IngestOutputs(extras.Outputs)
+		}
+		if err := converter.Validate(); err != nil {
+			cobra.CheckErr(fmt.Errorf("invalid %s metadata: %w", format.Names[0], err))
+		}
+		data, err := converter.Serialize(serialization)
+		cobra.CheckErr(err)
 
-	converter.Ingest(spec)
-	data, err := converter.Serialize("")
-	cobra.CheckErr(err)
+		if outFolder == "" {
+			fmt.Println(string(data))
+			continue
+		}
 
-	fmt.Println(string(data))
+		cobra.CheckErr(os.MkdirAll(outFolder, 0755))
+		path := filepath.Join(outFolder, format.SerializedFileName(serialization))
+		cobra.CheckErr(os.WriteFile(path, data, 0644))
+		fmt.Println("created", path)
+	}
 }
 
 func init() {
 
-	generateCmd.Flags().String("format", "", "Format to generate the metadata in; defaults to schema.org")
+	generateCmd.Flags().String("format", "", "Comma separated formats to generate the metadata in; defaults to schema.org")
+	generateCmd.Flags().String("out", "", "Folder to write the metadata files to, instead of printing them")
+	generateCmd.Flags().String("serialization", "", fmt.Sprintf("Serialization of the metadata, one of %v; defaults to pretty JSON-LD", metadata.Serializations))
+	generateCmd.Flags().String("docker-image", "", "Docker image of the tool, used by formats that run it, like cwl")
+	generateCmd.Flags().Bool("list-formats", false, "List all available formats")
 	rootCmd.AddCommand(generateCmd)
 }
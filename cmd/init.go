@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hydrocode-de/gotap/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init <toolname>",
+	Short: "Scaffold a new tool-spec compliant tool",
+	Long: `Creates the file layout of a new tool.
+
+The tool is created in a folder named after the tool, unless --dir is given.
+It contains the src/tool.yml, a run.<lang> entrypoint, CITATION.cff and
+LICENSE, an in/inputs.json with placeholders for all declared parameters
+and a Dockerfile, which installs gotap.
+
+All settings can be passed as flags. Use --interactive to be prompted for
+everything that was not passed.`,
+	Args: cobra.ExactArgs(1),
+	Run:  initTool,
+}
+
+func initTool(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	dir, _ := flags.GetString("dir")
+	interactive, _ := flags.GetBool("interactive")
+	force, _ := flags.GetBool("force")
+
+	tool := scaffold.Tool{Name: args[0]}
+	tool.Title, _ = flags.GetString("title")
+	tool.Description, _ = flags.GetString("description")
+	tool.Version, _ = flags.GetString("tool-version")
+	tool.Lang, _ = flags.GetString("lang")
+	tool.License, _ = flags.GetString("license")
+	tool.GotapRelease, _ = flags.GetString("gotap-release")
+
+	authors, _ := flags.GetStringArray("author")
+	for _, author := range authors {
+		tool.Authors = append(tool.Authors, scaffold.ParseAuthor(author))
+	}
+	params, _ := flags.GetStringArray("param")
+	for _, value := range params {
+		param, err := scaffold.ParseParameter(value)
+		cobra.CheckErr(err)
+		tool.Parameters = append(tool.Parameters, param)
+	}
+	datasets, _ := flags.GetStringArray("data")
+	for _, value := range datasets {
+		data, err := scaffold.ParseDataset(value)
+		cobra.CheckErr(err)
+		tool.Data = append(tool.Data, data)
+	}
+
+	if interactive {
+		promptTool(&tool, flags.Changed)
+	}
+	if tool.Title == "" {
+		tool.Title = tool.Name
+	}
+	if dir == "" {
+		dir = tool.Name
+	}
+
+	created, err := scaffold.Create(dir, tool, force)
+	for _, path := range created {
+		fmt.Println("created", path)
+	}
+	cobra.CheckErr(err)
+}
+
+// promptTool asks for all settings, which were not passed as flags.
+func promptTool(tool *scaffold.Tool, changed func(string) bool) {
+	reader := bufio.NewReader(os.Stdin)
+	prompt := func(label string, value string) string {
+		if value != "" {
+			fmt.Printf("%s [%s]: ", label, value)
+		} else {
+			fmt.Printf("%s: ", label)
+		}
+		answer, _ := reader.ReadString('\n')
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer
+		}
+		return value
+	}
+
+	if !changed("title") {
+		tool.Title = prompt("Title", tool.Name)
+	}
+	if !changed("description") {
+		tool.Description = prompt("Description", tool.Description)
+	}
+	if !changed("tool-version") {
+		tool.Version = prompt("Version", tool.Version)
+	}
+	if !changed("lang") {
+		languages := make([]string, 0, len(scaffold.Languages))
+		for lang := range scaffold.Languages {
+			languages = append(languages, lang)
+		}
+		sort.Strings(languages)
+		tool.Lang = prompt(fmt.Sprintf("Language (%s)", strings.Join(languages, ", ")), tool.Lang)
+	}
+	if !changed("license") {
+		tool.License = prompt("License (SPDX id)", tool.License)
+	}
+	if !changed("author") {
+		for {
+			author := prompt("Author (Given Family <email>, empty to finish)", "")
+			if author == "" {
+				break
+			}
+			tool.Authors = append(tool.Authors, scaffold.ParseAuthor(author))
+		}
+	}
+	if !changed("param") {
+		for {
+			value := prompt(fmt.Sprintf("Parameter (name:type with type one of %v, empty to finish)", scaffold.ParameterTypes), "")
+			if value == "" {
+				break
+			}
+			param, err := scaffold.ParseParameter(value)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tool.Parameters = append(tool.Parameters, param)
+		}
+	}
+	if !changed("data") {
+		for {
+			value := prompt("Data (name:extension, empty to finish)", "")
+			if value == "" {
+				break
+			}
+			data, err := scaffold.ParseDataset(value)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tool.Data = append(tool.Data, data)
+		}
+	}
+}
+
+func init() {
+	initCmd.Flags().String("dir", "", "Folder to create the tool in; defaults to the toolname")
+	initCmd.Flags().String("title", "", "Title of the tool")
+	initCmd.Flags().String("description", "", "Description of the tool")
+	initCmd.Flags().String("tool-version", "0.1.0", "Version of the tool")
+	initCmd.Flags().String("lang", "py", "Language of the run entrypoint: sh, py, R, jl, pl, m or js")
+	initCmd.Flags().String("license", "MIT", "SPDX id of the license")
+	initCmd.Flags().StringArray("author", nil, "Author as \"Given Family <email>\"; can be repeated")
+	initCmd.Flags().StringArray("param", nil, "Parameter as name:type, append [] to the type for arrays; can be repeated")
+	initCmd.Flags().StringArray("data", nil, "Dataset as name:extension; can be repeated")
+	initCmd.Flags().String("gotap-release", "latest", "gotap release installed in the Dockerfile")
+	initCmd.Flags().BoolP("interactive", "i", false, "Prompt for all settings not passed as flags")
+	initCmd.Flags().Bool("force", false, "Overwrite existing files")
+
+	rootCmd.AddCommand(initCmd)
+}
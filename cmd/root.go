@@ -30,8 +30,9 @@ import (
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "gotap",
-	Short: "Shim to tap tool-spec",
+	Use:     "gotap",
+	Version: config.Version,
+	Short:   "Shim to tap tool-spec",
 	Long: `Shim to tap tool-spec compliant metadata.
 
 This tool is used inside docker containers, which were
@@ -3,13 +3,22 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	goio "io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/hydrocode-de/gotap/internal/cache"
+	"github.com/hydrocode-de/gotap/internal/checksum"
 	"github.com/hydrocode-de/gotap/internal/config"
 	"github.com/hydrocode-de/gotap/internal/input"
 	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/metadata/crate"
+	"github.com/hydrocode-de/gotap/internal/metadata/prov"
 	"github.com/hydrocode-de/gotap/internal/validation"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
 	"github.com/spf13/cobra"
 )
 
@@ -26,12 +35,212 @@ finally the tool is executed.`,
 	Run:                execute,
 }
 
-func execute(cmd *cobra.Command, args []string) {
-	var failOnWarnings bool
-	for _, arg := range args {
-		if arg == "--fail-on-warnings" {
-			failOnWarnings = true
+// runValueFlags maps the flags only understood by run to their config keys.
+// They are consumed before the remaining args are parsed as tool inputs.
+var runValueFlags = map[string]string{
+	"--timeout":             "timeout",
+	"--grace-period":        "grace_period",
+	"--output-buffer-size":  "output_buffer_size",
+	"--sample-interval":     "sample_interval",
+	"--resource-log":        "resource_log",
+	"--memory-limit":        "memory_limit",
+	"--cpu-time-limit":      "cpu_time_limit",
+	"--open-files-limit":    "open_files_limit",
+	"--process-limit":       "process_limit",
+	"--cache-dir":           "cache_dir",
+	"--checksum":            "checksum",
+	"--env-param-prefix":    "env_param_prefix",
+	"--env-data-prefix":     "env_data_prefix",
+	"--env-name-case":       "env_name_case",
+	"--env-array-format":    "env_array_format",
+	"--env-array-delimiter": "env_array_delimiter",
+}
+
+var runBoolFlags = map[string]string{
+	"--fail-on-warnings": "fail_on_warnings",
+	"--ro-crate":         "ro_crate",
+	"--timestamps":       "timestamps",
+	"--stream-names":     "stream_names",
+	"--input-env":        "input_env",
+}
+
+// runNegatedFlags disable settings, which are enabled by default
+var runNegatedFlags = map[string]string{
+	"--no-provenance": "provenance",
+}
+
+// parseRunFlags accepts both --flag value and --flag=value. A value flag
+// without a value is an error, instead of being passed on to the tool. The
+// names of the flags found are returned as well.
+func parseRunFlags(args []string) ([]string, []string, error) {
+	v := config.GetViper()
+	var remainingArgs []string
+	var used []string
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+
+		if key, ok := runValueFlags[name]; ok {
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, nil, fmt.Errorf("flag %s needs a value", name)
+				}
+				i++ // Skip the value
+				value = args[i]
+			}
+			v.Set(key, value)
+			used = append(used, name)
+			continue
 		}
+
+		key, ok := runBoolFlags[name]
+		negated := false
+		if !ok {
+			key, ok = runNegatedFlags[name]
+			negated = true
+		}
+		if ok {
+			enabled := true
+			if hasValue {
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("flag %s expects true or false, got %s", name, value)
+				}
+				enabled = parsed
+			}
+			v.Set(key, enabled != negated)
+			used = append(used, name)
+			continue
+		}
+
+		remainingArgs = append(remainingArgs, args[i])
+	}
+
+	return remainingArgs, used, nil
+}
+
+// checkRunFlags rejects run flags, which have the name of a parameter or
+// dataset of the tool, as it is unclear which of both was meant.
+func checkRunFlags(spec toolspec.ToolSpec, used []string) error {
+	for _, flag := range used {
+		name := strings.TrimPrefix(flag, "--")
+		_, isParameter := spec.Parameters[name]
+		_, isData := spec.Data[name]
+		if isParameter || isData {
+			return fmt.Errorf("%s is a flag of run, but tool %s has an input with the same name. Set the input in inputs.json or with prepare", flag, spec.Name)
+		}
+	}
+	return nil
+}
+
+func executionOptions(spec toolspec.ToolSpec) (input.ExecutionOptions, error) {
+	v := config.GetViper()
+
+	extras, err := io.ReadToolExtras(v.GetString("spec_file"), spec.Name)
+	if err != nil {
+		return input.ExecutionOptions{}, err
+	}
+
+	timeoutValue := v.GetString("timeout")
+	if timeoutValue == "" {
+		timeoutValue = extras.Timeout
+	}
+	timeout, err := config.ParseDuration(timeoutValue)
+	if err != nil {
+		return input.ExecutionOptions{}, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	gracePeriod, err := config.ParseDuration(v.GetString("grace_period"))
+	if err != nil {
+		return input.ExecutionOptions{}, fmt.Errorf("invalid grace period: %w", err)
+	}
+
+	sampleInterval, err := config.ParseDuration(v.GetString("sample_interval"))
+	if err != nil {
+		return input.ExecutionOptions{}, fmt.Errorf("invalid sample interval: %w", err)
+	}
+
+	limits, err := resourceLimits(extras.Limits)
+	if err != nil {
+		return input.ExecutionOptions{}, err
+	}
+
+	if algorithm := v.GetString("checksum"); algorithm != "none" && !slices.Contains(checksum.Algorithms(), algorithm) {
+		return input.ExecutionOptions{}, fmt.Errorf("invalid checksum %s, use one of %v or none", algorithm, checksum.Algorithms())
+	}
+
+	inputEnv := input.EnvOptions{
+		Enabled:        v.GetBool("input_env"),
+		ParamPrefix:    v.GetString("env_param_prefix"),
+		DataPrefix:     v.GetString("env_data_prefix"),
+		NameCase:       v.GetString("env_name_case"),
+		ArrayFormat:    v.GetString("env_array_format"),
+		ArrayDelimiter: v.GetString("env_array_delimiter"),
+	}
+	if err := inputEnv.Validate(); err != nil {
+		return input.ExecutionOptions{}, err
+	}
+
+	return input.ExecutionOptions{
+		Timeout:          timeout,
+		GracePeriod:      gracePeriod,
+		OutputBufferSize: v.GetInt("output_buffer_size"),
+		Timestamps:       v.GetBool("timestamps"),
+		StreamNames:      v.GetBool("stream_names"),
+		SampleInterval:   sampleInterval,
+		Limits:           limits,
+		InputEnv:         inputEnv,
+	}, nil
+}
+
+// resourceLimits merges the limits from flags and env with the ones in
+// tool.yml. Flags and env take precedence.
+func resourceLimits(declared io.ToolLimits) (input.ResourceLimits, error) {
+	v := config.GetViper()
+
+	memoryValue := v.GetString("memory_limit")
+	if memoryValue == "" {
+		memoryValue = declared.Memory
+	}
+	memory, err := config.ParseSize(memoryValue)
+	if err != nil {
+		return input.ResourceLimits{}, fmt.Errorf("invalid memory limit: %w", err)
+	}
+
+	cpuTimeValue := v.GetString("cpu_time_limit")
+	if cpuTimeValue == "" {
+		cpuTimeValue = declared.CPUTime
+	}
+	cpuTime, err := config.ParseDuration(cpuTimeValue)
+	if err != nil {
+		return input.ResourceLimits{}, fmt.Errorf("invalid cpu time limit: %w", err)
+	}
+
+	openFiles := v.GetUint64("open_files_limit")
+	if openFiles == 0 {
+		openFiles = declared.OpenFiles
+	}
+	processes := v.GetUint64("process_limit")
+	if processes == 0 {
+		processes = declared.Processes
+	}
+
+	return input.ResourceLimits{
+		Memory:    memory,
+		CPUTime:   cpuTime,
+		OpenFiles: openFiles,
+		Processes: processes,
+	}, nil
+}
+
+func execute(cmd *cobra.Command, args []string) {
+	args, used, err := parseRunFlags(args)
+	cobra.CheckErr(err)
+	failOnWarnings := config.GetViper().GetBool("fail_on_warnings")
+
+	// errors loading the spec are reported by PrepareInputs
+	if spec, err := validation.LoadSpec(args); err == nil {
+		cobra.CheckErr(checkRunFlags(spec, used))
 	}
 
 	dry, err := PrepareInputs(cmd, args)
@@ -63,20 +272,260 @@ func execute(cmd *cobra.Command, args []string) {
 	// execute the command finally. This can later be replaced by
 	// by logging, tracing, etc.
 	outputFolder := config.GetViper().GetString("output_folder")
+	opts, err := executionOptions(result.ToolSpec)
+	cobra.CheckErr(err)
+
+	// the datasets are hashed once, before the tool could change them. The
+	// sums are shared by the cache key, the MANIFEST.inputs and provenance
+	cacheDir := config.GetViper().GetString("cache_dir")
+	algorithm := config.GetViper().GetString("checksum")
+	var datasets checksum.Datasets
+	if cacheDir != "" || algorithm != "none" || config.GetViper().GetBool("provenance") {
+		inputFile := config.GetViper().GetString("input_file")
+		paths := make(map[string]string, len(result.ToolInput.Datasets))
+		for name, dataPath := range result.ToolInput.Datasets {
+			paths[name] = io.ResolveDataPath(dataPath, inputFile)
+		}
+		datasets, err = checksum.HashDatasets(paths, algorithm)
+		cobra.CheckErr(err)
+	}
+
+	// identical runs are restored from the cache instead of executed
+	var cacheKey string
+	if cacheDir != "" {
+		image := config.GetViper().GetString("image_digest")
+		if image == "" {
+			image = config.GetViper().GetString("docker_image")
+		}
+		cacheKey, err = cache.Key(result.ToolSpec, toolVersion(result.ToolSpec), image, result.ToolInput, datasets.Sums)
+		cobra.CheckErr(err)
+
+		entry, ok, err := cache.Lookup(cacheDir, cacheKey)
+		cobra.CheckErr(err)
+		if ok {
+			restoreFromCache(entry, outputFolder, algorithm, datasets)
+			return
+		}
+	}
 
-	cmdResult, err := input.ExecuteCommand(command)
+	// files already in the output folder are not outputs of this run
+	before, err := io.SnapshotFolder(outputFolder)
 	cobra.CheckErr(err)
 
-	if cmdResult.Stderr != nil {
-		os.WriteFile(filepath.Join(outputFolder, "STDERR"), cmdResult.Stderr, 0644)
+	// the output is teed to the terminal and the output folder while the
+	// tool is running
+	stdoutFile, err := os.Create(filepath.Join(outputFolder, "STDOUT"))
+	cobra.CheckErr(err)
+	defer stdoutFile.Close()
+	stderrFile, err := os.Create(filepath.Join(outputFolder, "STDERR"))
+	cobra.CheckErr(err)
+	defer stderrFile.Close()
+
+	opts.ExportInputs(result.ToolSpec, result.ToolInput, config.GetViper().GetString("input_file"))
+	opts.Stdout = goio.MultiWriter(os.Stdout, stdoutFile)
+	opts.Stderr = goio.MultiWriter(os.Stderr, stderrFile)
+
+	if format := config.GetViper().GetString("resource_log"); format != "" {
+		resourceFile, err := os.Create(filepath.Join(outputFolder, "resources."+format))
+		cobra.CheckErr(err)
+		defer resourceFile.Close()
+
+		sampleWriter, err := input.NewSampleWriter(resourceFile, format)
+		cobra.CheckErr(err)
+		opts.OnSample = func(sample input.ResourceSample) {
+			sampleWriter.Write(sample)
+		}
 	}
-	if cmdResult.Stdout != nil {
-		os.WriteFile(filepath.Join(outputFolder, "STDOUT"), cmdResult.Stdout, 0644)
+
+	if algorithm != "none" {
+		err = checksum.WriteManifestFile(filepath.Join(outputFolder, checksum.InputsManifestFile), datasets.Entries)
+		cobra.CheckErr(err)
+	}
+
+	cmdResult, err := input.ExecuteCommand(command, opts)
+	cobra.CheckErr(err)
+	cmdResult.CacheKey = cacheKey
+	if algorithm != "none" {
+		cmdResult.ManifestAlgorithm = algorithm
+	}
+
+	// a tool, which exited successfully, still fails without its outputs
+	if cmdResult.ExitCode == 0 {
+		cmdResult.OutputErrors, err = validation.ValidateDeclaredOutputs(config.GetViper().GetString("spec_file"), result.ToolSpec.Name, outputFolder, before)
+		cobra.CheckErr(err)
 	}
 	jsonResult, err := json.MarshalIndent(cmdResult, "", "  ")
 	if err == nil {
 		os.WriteFile(filepath.Join(outputFolder, "_metadata.json"), jsonResult, 0644)
 	}
+
+	if config.GetViper().GetBool("provenance") {
+		err := writeProvenance(result, datasets, outputFolder, before, cmdResult)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write the provenance record: %s\n", err)
+		}
+	}
+
+	// the crate lists all other files, including the manifest written after
+	var pending []string
+	if algorithm != "none" {
+		pending = append(pending, checksum.ManifestFile)
+	}
+	if config.GetViper().GetBool("ro_crate") {
+		roCrate, err := crate.BuildRunCrate(crate.Run{
+			Spec:         result.ToolSpec,
+			Input:        result.ToolInput,
+			InputFile:    config.GetViper().GetString("input_file"),
+			OutputFolder: outputFolder,
+			StartTime:    cmdResult.StartTime,
+			EndTime:      cmdResult.EndTime,
+			ExitCode:     cmdResult.ExitCode,
+			Pending:      pending,
+		})
+		if err == nil {
+			err = os.WriteFile(filepath.Join(outputFolder, crate.FileName), roCrate, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write the RO-Crate: %s\n", err)
+		}
+	}
+
+	// the manifest is written last, to cover all other files
+	if algorithm != "none" {
+		writeManifest(outputFolder, algorithm)
+	}
+
+	if len(cmdResult.OutputErrors) > 0 {
+		fmt.Println("FAIL")
+		for _, err := range cmdResult.OutputErrors {
+			fmt.Println(io.WriteValidationError(err, true))
+		}
+		os.Exit(1)
+	}
+
+	// only successful runs are cached, without the files of earlier runs
+	if cacheDir != "" && cmdResult.ExitCode == 0 {
+		written, err := before.Changed(outputFolder)
+		if err == nil {
+			_, err = cache.Store(cacheDir, cacheKey, result.ToolSpec.Name, outputFolder, written)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to cache the outputs: %s\n", err)
+		}
+	}
+
+	// exit with the status of the tool, so that orchestrators see it
+	if cmdResult.ExitCode != 0 {
+		os.Exit(cmdResult.ExitCode)
+	}
+}
+
+func writeManifest(outputFolder string, algorithm string) {
+	manifest, err := checksum.FolderManifest(outputFolder, algorithm, checksum.ManifestFile)
+	if err == nil {
+		err = checksum.WriteManifestFile(filepath.Join(outputFolder, checksum.ManifestFile), manifest)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the manifest: %s\n", err)
+	}
+}
+
+// toolVersion prefers the version from tool.yml over the one from
+// CITATION.cff.
+func toolVersion(spec toolspec.ToolSpec) string {
+	extras, err := io.ReadToolExtras(config.GetViper().GetString("spec_file"), spec.Name)
+	if err == nil && extras.Version != "" {
+		return extras.Version
+	}
+	return spec.Citation.Version
+}
+
+// writeProvenance records the files written during the run as generated by
+// it. Leftovers of earlier runs and the records of gotap itself are left out.
+func writeProvenance(result validation.ValidationResult, datasets checksum.Datasets, outputFolder string, before io.FolderSnapshot, cmdResult input.ExecutionResult) error {
+	written, err := before.Changed(outputFolder)
+	if err != nil {
+		return err
+	}
+	v := config.GetViper()
+	records := []string{"_metadata.json", checksum.InputsManifestFile}
+	if format := v.GetString("resource_log"); format != "" {
+		records = append(records, "resources."+format)
+	}
+	generated := make([]string, 0, len(written))
+	for _, name := range written {
+		if !slices.Contains(records, name) {
+			generated = append(generated, name)
+		}
+	}
+
+	document, err := prov.BuildRunProvenance(prov.Run{
+		Spec:         result.ToolSpec,
+		ToolVersion:  toolVersion(result.ToolSpec),
+		Image:        v.GetString("docker_image"),
+		ImageDigest:  v.GetString("image_digest"),
+		Input:        result.ToolInput,
+		Datasets:     datasets,
+		OutputFolder: outputFolder,
+		Generated:    generated,
+		StartTime:    cmdResult.StartTime,
+		EndTime:      cmdResult.EndTime,
+		ExitCode:     cmdResult.ExitCode,
+		GotapVersion: cmdResult.GotapVersion,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputFolder, prov.FileName), document, 0644)
+}
+
+// restoreFromCache copies the cached outputs to the output folder and
+// replays the output of the tool. The restored _metadata.json is marked as
+// cache hit, so the MANIFEST files are written again with the algorithm of
+// this run. Without one, the algorithm of the cached run is kept.
+func restoreFromCache(entry cache.Entry, outputFolder string, algorithm string, datasets checksum.Datasets) {
+	cobra.CheckErr(cache.Restore(entry, outputFolder))
+
+	if stdout, err := os.ReadFile(filepath.Join(outputFolder, "STDOUT")); err == nil {
+		os.Stdout.Write(stdout)
+	}
+	if stderr, err := os.ReadFile(filepath.Join(outputFolder, "STDERR")); err == nil {
+		os.Stderr.Write(stderr)
+	}
+
+	metadataFile := filepath.Join(outputFolder, "_metadata.json")
+	var cmdResult input.ExecutionResult
+	buffer, err := os.ReadFile(metadataFile)
+	if err == nil {
+		err = json.Unmarshal(buffer, &cmdResult)
+	}
+	cobra.CheckErr(err)
+
+	cmdResult.CacheHit = true
+	cmdResult.CacheKey = entry.Key
+	if algorithm != "none" {
+		cmdResult.ManifestAlgorithm = algorithm
+		err = checksum.WriteManifestFile(filepath.Join(outputFolder, checksum.InputsManifestFile), datasets.Entries)
+		cobra.CheckErr(err)
+	}
+	jsonResult, err := json.MarshalIndent(cmdResult, "", "  ")
+	if err == nil {
+		os.WriteFile(metadataFile, jsonResult, 0644)
+	}
+	if cmdResult.ManifestAlgorithm != "" {
+		writeManifest(outputFolder, cmdResult.ManifestAlgorithm)
+	}
+}
+
+// limitedExecCmd is used by run itself, to set resource limits right before
+// the tool is started
+var limitedExecCmd = &cobra.Command{
+	Use:                input.LimitedExecCommand,
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cobra.CheckErr(input.ExecLimited(args))
+	},
 }
 
 func init() {
@@ -84,5 +533,27 @@ func init() {
 	runCmd.Flags().Bool("update-inputs", false, "Update the inputs.json if arguments are provided and the file already exists.")
 
 	runCmd.Flags().Bool("fail-on-warnings", false, "Fail the tool if there are warnings.")
+	runCmd.Flags().String("timeout", "", "Wall-clock timeout for the tool, e.g. 2h or 90m. Overrides the timeout in tool.yml.")
+	runCmd.Flags().String("grace-period", "", "Time between SIGTERM and SIGKILL, once the timeout is reached.")
+	runCmd.Flags().Int("output-buffer-size", 0, "Maximum number of bytes of STDOUT and STDERR kept in memory.")
+	runCmd.Flags().String("sample-interval", "", "Interval of the resource usage sampling; defaults to 100ms.")
+	runCmd.Flags().String("resource-log", "", "Write every resource sample to the output folder as csv or jsonl.")
+	runCmd.Flags().String("memory-limit", "", "Memory limit of the tool, e.g. 512M or 2G. Uses a cgroup, if the memory controller is delegated to the cgroup v2 of gotap, and RLIMIT_AS otherwise.")
+	runCmd.Flags().String("cpu-time-limit", "", "CPU time limit of the tool, e.g. 1h.")
+	runCmd.Flags().Uint64("open-files-limit", 0, "Maximum number of open files per process of the tool.")
+	runCmd.Flags().Uint64("process-limit", 0, "Maximum number of processes of the tool. Needs the pids controller delegated to the cgroup v2 of gotap; the rlimit fallback counts all processes of the user and is ignored for root.")
+	runCmd.Flags().String("cache-dir", "", "Restore the outputs of identical runs from this cache, and cache successful runs. The key covers tool.yml, the tool version and image and the inputs, but not the tool's code.")
+	runCmd.Flags().Bool("ro-crate", false, "Describe the run as RO-Crate in the output folder.")
+	runCmd.Flags().String("checksum", "", fmt.Sprintf("Checksum algorithm of the MANIFEST files, one of %v or none; defaults to sha256.", checksum.Algorithms()))
+	runCmd.Flags().Bool("no-provenance", false, "Do not write the W3C PROV record of the run to the output folder.")
+	runCmd.Flags().Bool("input-env", false, "Export every parameter and dataset as environment variable of the tool.")
+	runCmd.Flags().String("env-param-prefix", "", "Prefix of the parameter variables; defaults to TAP_PARAM_.")
+	runCmd.Flags().String("env-data-prefix", "", "Prefix of the dataset variables; defaults to TAP_DATA_.")
+	runCmd.Flags().String("env-name-case", "", "Case of the variable names: upper, lower or keep; defaults to upper.")
+	runCmd.Flags().String("env-array-format", "", "Format of array parameters: json or join; defaults to json.")
+	runCmd.Flags().String("env-array-delimiter", "", "Delimiter of joined array parameters; defaults to a comma.")
+	runCmd.Flags().Bool("timestamps", false, "Prefix every line of the tool output with a timestamp.")
+	runCmd.Flags().Bool("stream-names", false, "Prefix every line of the tool output with the stream name.")
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(limitedExecCmd)
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hydrocode-de/gotap/internal/schema"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema [toolname]",
+	Short: "Print the JSON Schema of the tool's inputs.json",
+	Long: `Print a JSON Schema (draft 2020-12) for the inputs.json of a tool.
+
+The schema is derived from tool.yml and describes the same constraints
+which are checked by verify, so that frontends can validate inputs
+before they are sent to the tool.`,
+	Run: printSchema,
+}
+
+func printSchema(cmd *cobra.Command, args []string) {
+	spec, err := validation.LoadSpec(args)
+	cobra.CheckErr(err)
+
+	inputSchema, err := schema.Inputs(spec)
+	cobra.CheckErr(err)
+
+	data, err := json.MarshalIndent(inputSchema, "", "  ")
+	cobra.CheckErr(err)
+	fmt.Println(string(data))
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
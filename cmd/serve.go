@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/config"
+	"github.com/hydrocode-de/gotap/internal/server"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Expose the tools over HTTP",
+	Long: `Run a HTTP server, which exposes the tools of tool.yml as REST API.
+
+  GET  /tools                          list all tools
+  GET  /tools/{name}                   the tool specification
+  GET  /tools/{name}/metadata          metadata, ?format= and ?serialization= like generate
+  GET  /tools/{name}/schema            JSON Schema of the inputs.json
+  POST /tools/{name}/validate          validate an inputs.json
+  POST /tools/{name}/jobs              validate an inputs.json and run the tool
+  GET  /jobs                           list all jobs
+  GET  /jobs/{id}                      status and result of a job
+  GET  /jobs/{id}/logs                 ?stream=stdout|stderr and ?follow=true
+  GET  /jobs/{id}/outputs              list the output files of a job
+  GET  /jobs/{id}/outputs/{file}       download an output file
+
+Inputs are posted as inputs.json body, or as multipart form with an
+inputs.json part and the data files. Every job runs in its own folder
+below --jobs-dir, with the same timeouts and limits as run.
+
+The tool still reads the inputs.json and writes to the output folder it
+expects, usually /in/inputs.json and /out. The files of the job are
+mounted there for the tool only, which needs mount namespaces (root, or
+CAP_SYS_ADMIN in a container).`,
+	Run: serve,
+}
+
+func serve(cmd *cobra.Command, args []string) {
+	v := config.GetViper()
+	v.BindPFlag("addr", cmd.Flags().Lookup("addr"))
+	v.BindPFlag("jobs_dir", cmd.Flags().Lookup("jobs-dir"))
+	v.BindPFlag("max_jobs", cmd.Flags().Lookup("max-jobs"))
+	v.BindPFlag("fail_on_warnings", cmd.Flags().Lookup("fail-on-warnings"))
+	v.BindPFlag("job_ttl", cmd.Flags().Lookup("job-ttl"))
+	v.BindPFlag("max_queued_jobs", cmd.Flags().Lookup("max-queued-jobs"))
+	v.BindPFlag("max_upload_size", cmd.Flags().Lookup("max-upload-size"))
+
+	jobTTL, err := config.ParseDuration(v.GetString("job_ttl"))
+	cobra.CheckErr(err)
+	maxUploadSize, err := config.ParseSize(v.GetString("max_upload_size"))
+	cobra.CheckErr(err)
+
+	srv, err := server.New(server.Options{
+		Files:            validation.ConfiguredFiles(),
+		JobsDir:          v.GetString("jobs_dir"),
+		MaxJobs:          v.GetInt("max_jobs"),
+		FailOnWarnings:   v.GetBool("fail_on_warnings"),
+		DockerImage:      v.GetString("docker_image"),
+		ExecutionOptions: executionOptions,
+		OutputFolder:     v.GetString("output_folder"),
+		JobTTL:           jobTTL,
+		MaxQueuedJobs:    v.GetInt("max_queued_jobs"),
+		MaxUploadSize:    int64(maxUploadSize),
+	})
+	cobra.CheckErr(err)
+
+	httpServer := &http.Server{
+		Addr:    v.GetString("addr"),
+		Handler: srv.Handler(),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Println("serving on", httpServer.Addr)
+	err = httpServer.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		cobra.CheckErr(err)
+	}
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on; the API has no authentication, so expose it only behind an authenticating proxy")
+	serveCmd.Flags().String("jobs-dir", "", "Folder for the inputs and outputs of jobs; defaults to a temporary folder")
+	serveCmd.Flags().Int("max-jobs", 1, "Number of jobs running at the same time")
+	serveCmd.Flags().String("job-ttl", "24h", "Remove finished jobs and their folders after this time; 0 keeps them")
+	serveCmd.Flags().Int("max-queued-jobs", 100, "Number of jobs waiting for a free slot; further jobs are rejected with 503")
+	serveCmd.Flags().String("max-upload-size", "1GiB", "Maximum size of a request with inputs, including all data files; larger ones are rejected with 413")
+	serveCmd.Flags().Bool("fail-on-warnings", false, "Reject jobs if there are warnings.")
+	rootCmd.AddCommand(serveCmd)
+}
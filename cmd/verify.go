@@ -2,13 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/hydrocode-de/gotap/internal/config"
 	"github.com/hydrocode-de/gotap/internal/io"
 	"github.com/hydrocode-de/gotap/internal/validation"
 	"github.com/spf13/cobra"
 )
 
 var verbose bool
+var output string
 
 // verifyCmd represents the verify command
 var verifyCmd = &cobra.Command{
@@ -27,6 +30,32 @@ func verify(cmd *cobra.Command, args []string) {
 	validation, err := validation.LoadAndValidateSpec(args)
 	cobra.CheckErr(err)
 
+	status := validation.Status()
+	if output != "text" {
+		v := config.GetViper()
+		report, err := io.WriteValidationReport(io.ValidationReport{
+			Tool:         validation.ToolSpec.Name,
+			Status:       status,
+			SpecFile:     v.GetString("spec_file"),
+			InputFile:    v.GetString("input_file"),
+			ErrorCount:   validation.ErrorCount(),
+			WarningCount: validation.WarningCount(),
+			Errors:       validation.Errors,
+			Warnings:     validation.Warnings,
+		}, output)
+		cobra.CheckErr(err)
+
+		fmt.Println(string(report))
+	} else {
+		writeTextReport(validation)
+	}
+
+	if status == "FAIL" {
+		os.Exit(1)
+	}
+}
+
+func writeTextReport(validation validation.ValidationResult) {
 	errorCount := validation.ErrorCount()
 	warningCount := validation.WarningCount()
 	hasErrors := errorCount > 0
@@ -35,13 +64,9 @@ func verify(cmd *cobra.Command, args []string) {
 	warnings := validation.Warnings
 	errors := validation.Errors
 
+	fmt.Println(validation.Status())
 	if !hasErrors && !hasWarnings {
-		fmt.Println("OK")
 		return
-	} else if hasErrors {
-		fmt.Println("FAIL")
-	} else {
-		fmt.Println("WARN")
 	}
 
 	for _, warning := range warnings {
@@ -58,7 +83,7 @@ func verify(cmd *cobra.Command, args []string) {
 		fmt.Println(io.WriteValidationError(err, verbose))
 	}
 
-	if verbose && (hasErrors || hasWarnings) {
+	if verbose {
 		fmt.Println("--------------------------------")
 		fmt.Printf("ERRORS: %d     WARNINGS: %d\n", errorCount, warningCount)
 		fmt.Println("--------------------------------")
@@ -67,6 +92,7 @@ func verify(cmd *cobra.Command, args []string) {
 
 func init() {
 	verifyCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	verifyCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text, json, yaml, junit or sarif")
 
 	rootCmd.AddCommand(verifyCmd)
 }
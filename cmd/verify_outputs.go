@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hydrocode-de/gotap/internal/checksum"
+	"github.com/hydrocode-de/gotap/internal/config"
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/spf13/cobra"
+)
+
+// verifyOutputsCmd represents the verify-outputs command
+var verifyOutputsCmd = &cobra.Command{
+	Use:   "verify-outputs [folder]",
+	Short: "Verify an output folder against its MANIFEST",
+	Long: `Verify that the files of an output folder are unaltered.
+
+Every file listed in the MANIFEST written by run is hashed again and
+compared. Missing, changed and unexpected files are reported. The folder
+defaults to the output folder. The algorithm is read from _metadata.json,
+unless it is passed using --algorithm.
+
+If the folder has a MANIFEST.inputs, the datasets of the inputs.json are
+hashed again and compared to it as well. Pass --tool, if the inputs.json
+has inputs for more than one tool.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  verifyOutputs,
+}
+
+func verifyOutputs(cmd *cobra.Command, args []string) {
+	folder := config.GetViper().GetString("output_folder")
+	if len(args) > 0 {
+		folder = args[0]
+	}
+
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	if algorithm == "" {
+		algorithm = manifestAlgorithm(folder)
+	}
+
+	manifest, err := readManifestFile(filepath.Join(folder, checksum.ManifestFile))
+	cobra.CheckErr(err)
+
+	problems, err := checksum.VerifyFolder(folder, manifest, algorithm, checksum.ManifestFile)
+	cobra.CheckErr(err)
+
+	// the datasets are only verified, if the run recorded them
+	inputsManifest, err := readManifestFile(filepath.Join(folder, checksum.InputsManifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		cobra.CheckErr(err)
+	}
+	inputProblems := make([]checksum.Problem, 0)
+	if err == nil {
+		toolname, _ := cmd.Flags().GetString("tool")
+		paths, err := datasetPaths(config.GetViper().GetString("input_file"), toolname)
+		cobra.CheckErr(err)
+		inputProblems, err = checksum.VerifyDatasets(paths, inputsManifest, algorithm)
+		cobra.CheckErr(err)
+	}
+
+	if len(problems) == 0 && len(inputProblems) == 0 {
+		fmt.Printf("OK: %d files and %d dataset files verified using %s\n", len(manifest), len(inputsManifest), algorithm)
+		return
+	}
+
+	fmt.Println("FAIL")
+	for _, problem := range problems {
+		fmt.Printf("%-10s %s\n", problem.Kind, problem.Path)
+	}
+	for _, problem := range inputProblems {
+		fmt.Printf("%-10s %s (%s)\n", problem.Kind, problem.Path, checksum.InputsManifestFile)
+	}
+	os.Exit(1)
+}
+
+func readManifestFile(path string) ([]checksum.ManifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return checksum.ReadManifest(file)
+}
+
+// datasetPaths resolves the datasets of the tool in the inputs.json, like
+// run does. The tool may be left out, if the inputs.json has only one.
+func datasetPaths(inputFile string, toolname string) (map[string]string, error) {
+	inputs, err := io.ReadInputFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	if toolname == "" {
+		if len(inputs) != 1 {
+			return nil, fmt.Errorf("the input file has inputs for %d tools, pass one with --tool", len(inputs))
+		}
+		for name := range inputs {
+			toolname = name
+		}
+	}
+	tool, ok := inputs[toolname]
+	if !ok {
+		return nil, fmt.Errorf("the input file has no inputs for %s", toolname)
+	}
+
+	paths := make(map[string]string, len(tool.Datasets))
+	for name, dataPath := range tool.Datasets {
+		paths[name] = io.ResolveDataPath(dataPath, inputFile)
+	}
+	return paths, nil
+}
+
+// manifestAlgorithm reads the algorithm recorded by run. Older runs did not
+// record it, and used the default sha256.
+func manifestAlgorithm(folder string) string {
+	var metadata struct {
+		ManifestAlgorithm string `json:"manifest_algorithm"`
+	}
+	buffer, err := os.ReadFile(filepath.Join(folder, "_metadata.json"))
+	if err == nil && json.Unmarshal(buffer, &metadata) == nil && metadata.ManifestAlgorithm != "" {
+		return metadata.ManifestAlgorithm
+	}
+	return checksum.SHA256
+}
+
+func init() {
+	verifyOutputsCmd.Flags().String("algorithm", "", fmt.Sprintf("Checksum algorithm of the MANIFEST, one of %v", checksum.Algorithms()))
+	verifyOutputsCmd.Flags().String("tool", "", "Tool of the inputs.json, whose datasets are verified against MANIFEST.inputs")
+	rootCmd.AddCommand(verifyOutputsCmd)
+}
@@ -0,0 +1,40 @@
+#!/bin/bash
+# Runs a batch of two rows, which only differ in foo_string. The tool reads
+# the inputs.json and writes its result at the fixed locations, like tools
+# in a container do, so both rows need their own view of them.
+set -e
+cd "$(dirname "$0")"
+go build -o ../../../tap ../../../
+
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+cp -r ../in "$tmp/in"
+
+cat > "$tmp/tool.sh" <<EOF
+#!/bin/sh
+sleep 1
+grep -o '"foo_string": *"[^"]*"' "$tmp/in/inputs.json" > "$tmp/out/result.txt"
+EOF
+chmod +x "$tmp/tool.sh"
+
+cat > "$tmp/sweep.yml" <<'EOF'
+parameters:
+  foo_string: [one, two]
+EOF
+
+TAP_COMMAND="$tmp/tool.sh" TAP_INPUT_FILE="$tmp/in/inputs.json" TAP_OUTPUT_FOLDER="$tmp/out" \
+  ../../../tap batch foobar --sweep "$tmp/sweep.yml" --out "$tmp/batch" --parallel 2
+
+first=$(cat "$tmp/batch/1/out/result.txt")
+second=$(cat "$tmp/batch/2/out/result.txt")
+echo "row 1: $first"
+echo "row 2: $second"
+if [ "$first" = "$second" ] || ! grep -q one <<< "$first" || ! grep -q two <<< "$second"; then
+  echo "the rows did not get their own inputs" >&2
+  exit 1
+fi
+if [ -n "$(ls -A "$tmp/out" 2>/dev/null)" ]; then
+  echo "outputs were left in the shared output folder" >&2
+  exit 1
+fi
+echo "ok"
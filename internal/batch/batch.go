@@ -0,0 +1,282 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hydrocode-de/gotap/internal/input"
+	gotapio "github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// Options configure a batch run. The base inputs are used for everything,
+// which is not set by a row.
+type Options struct {
+	Files            validation.Files
+	Spec             toolspec.ToolSpec
+	Base             toolspec.ToolInput
+	OutputFolder     string
+	Parallel         int
+	FailOnWarnings   bool
+	ExecutionOptions func(spec toolspec.ToolSpec) (input.ExecutionOptions, error)
+	// Sandbox maps the inputs.json and output folder of each row to the
+	// locations the tool reads and writes
+	Sandbox *input.Sandbox
+}
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusInvalid   Status = "invalid"
+	StatusError     Status = "error"
+)
+
+// Result is the outcome of a single row
+type Result struct {
+	Run    string
+	Row    Row
+	Status Status
+	Error  string
+	Result *input.ExecutionResult
+}
+
+// Run creates an inputs.json for every row and runs the tool with it. Each
+// run gets its own folder in the output folder, named by the row number.
+func Run(rows []Row, opts Options) []Result {
+	if opts.Parallel <= 0 {
+		opts.Parallel = 1
+	}
+	width := len(strconv.Itoa(len(rows)))
+
+	results := make([]Result, len(rows))
+	slots := make(chan struct{}, opts.Parallel)
+	var wg sync.WaitGroup
+	for i, row := range rows {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			run := fmt.Sprintf("%0*d", width, i+1)
+			results[i] = runRow(run, row, opts)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func runRow(run string, row Row, opts Options) Result {
+	result := Result{Run: run, Row: row, Status: StatusError}
+	dir := filepath.Join(opts.OutputFolder, run)
+	outputPath := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	inputFile := filepath.Join(dir, "inputs.json")
+	if err := writeInputs(inputFile, row, opts); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	files := opts.Files
+	files.Input = inputFile
+	validated, err := validation.ValidateFiles(files, []string{opts.Spec.Name})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if validated.ErrorCount() > 0 || opts.FailOnWarnings && validated.WarningCount() > 0 {
+		result.Status = StatusInvalid
+		if validated.ErrorCount() > 0 {
+			result.Error = validated.Errors[0].Message
+		} else {
+			result.Error = validated.Warnings[0].Message
+		}
+		return result
+	}
+
+	command, err := input.ResolveCommand(opts.Spec)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	execOpts, err := opts.ExecutionOptions(opts.Spec)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	before, err := gotapio.SnapshotFolder(outputPath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	stdoutFile, err := os.Create(filepath.Join(outputPath, "STDOUT"))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer stdoutFile.Close()
+	stderrFile, err := os.Create(filepath.Join(outputPath, "STDERR"))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer stderrFile.Close()
+
+	if err := opts.Sandbox.Enter(&execOpts, inputFile, outputPath); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	execOpts.ExportInputs(validated.ToolSpec, validated.ToolInput, inputFile)
+	execOpts.Stdout = stdoutFile
+	execOpts.Stderr = stderrFile
+	execOpts.Env = append(execOpts.Env,
+		"TAP_INPUT_FILE="+opts.Sandbox.InputFile,
+		"TAP_OUTPUT_FOLDER="+opts.Sandbox.OutputFolder,
+		"RUN_TOOL="+opts.Spec.Name,
+	)
+
+	cmdResult, err := input.ExecuteCommand(command, execOpts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if cmdResult.ExitCode == 0 {
+		cmdResult.OutputErrors, err = validation.ValidateDeclaredOutputs(opts.Files.Spec, opts.Spec.Name, outputPath, before)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	jsonResult, err := json.MarshalIndent(cmdResult, "", "  ")
+	if err == nil {
+		os.WriteFile(filepath.Join(outputPath, "_metadata.json"), jsonResult, 0644)
+	}
+
+	result.Result = &cmdResult
+	result.Status = StatusFailed
+	if len(cmdResult.OutputErrors) > 0 {
+		result.Error = cmdResult.OutputErrors[0].Message
+	} else if cmdResult.ExitCode == 0 {
+		result.Status = StatusSucceeded
+	}
+	return result
+}
+
+// writeInputs merges the row into the base inputs. Data paths are resolved
+// against the original inputs.json, as the new one lives somewhere else.
+func writeInputs(path string, row Row, opts Options) error {
+	toolInput := toolspec.ToolInput{
+		Parameters: make(map[string]interface{}),
+		Datasets:   make(map[string]string),
+	}
+	for name, value := range opts.Base.Parameters {
+		toolInput.Parameters[name] = value
+	}
+	for name, dataPath := range opts.Base.Datasets {
+		toolInput.Datasets[name] = gotapio.ResolveDataPath(dataPath, opts.Files.Input)
+	}
+
+	for name, value := range row {
+		if _, ok := opts.Spec.Data[name]; ok {
+			toolInput.Datasets[name] = fmt.Sprint(value)
+			continue
+		}
+		toolInput.Parameters[name] = value
+	}
+
+	for name, dataPath := range toolInput.Datasets {
+		if absolute, err := filepath.Abs(dataPath); err == nil {
+			toolInput.Datasets[name] = absolute
+		}
+	}
+
+	jsonInput, err := gotapio.InputFileToJSON(toolspec.InputFile{opts.Spec.Name: toolInput})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(jsonInput), 0644)
+}
+
+// Columns lists all keys used by the rows in alphabetical order.
+func Columns(rows []Row) []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// SummaryHeader are the columns of the summary, which follow the row values
+var SummaryHeader = []string{"status", "exit_code", "termination_reason", "wall_time_seconds", "user_time_seconds", "system_time_seconds", "memory_max_bytes", "cpu_max_permille", "error"}
+
+// SummaryRecords returns the summary as table, starting with the header.
+func SummaryRecords(results []Result, columns []string) [][]string {
+	header := append(append([]string{"run"}, columns...), SummaryHeader...)
+	records := [][]string{header}
+
+	for _, result := range results {
+		record := []string{result.Run}
+		for _, column := range columns {
+			value, ok := result.Row[column]
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			if _, ok := value.([]interface{}); ok {
+				encoded, _ := json.Marshal(value)
+				record = append(record, string(encoded))
+			} else {
+				record = append(record, fmt.Sprint(value))
+			}
+		}
+
+		record = append(record, string(result.Status))
+		if result.Result != nil {
+			r := result.Result
+			record = append(record,
+				strconv.Itoa(r.ExitCode),
+				string(r.Termination),
+				strconv.FormatFloat(r.WallTime, 'f', 3, 64),
+				strconv.FormatFloat(r.UserTime, 'f', 3, 64),
+				strconv.FormatFloat(r.SystemTime, 'f', 3, 64),
+				strconv.FormatUint(r.MemoryMax, 10),
+				strconv.FormatUint(r.CPUMax, 10),
+			)
+		} else {
+			record = append(record, "", "", "", "", "", "", "")
+		}
+		record = append(record, result.Error)
+		records = append(records, record)
+	}
+	return records
+}
+
+func WriteSummary(w io.Writer, records [][]string) error {
+	writer := csv.NewWriter(w)
+	writer.WriteAll(records)
+	return writer.Error()
+}
@@ -0,0 +1,195 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Row holds the parameters and datasets of a single run. Keys, which are
+// datasets of the tool, are used as data paths.
+type Row map[string]interface{}
+
+// Range is a sweep over numbers, including start and stop.
+type Range struct {
+	Start float64 `yaml:"start"`
+	Stop  float64 `yaml:"stop"`
+	Step  float64 `yaml:"step"`
+}
+
+func (r Range) values() ([]interface{}, error) {
+	if r.Step <= 0 {
+		return nil, fmt.Errorf("the step of a range has to be positive")
+	}
+	count := int(math.Floor((r.Stop-r.Start)/r.Step+1e-9)) + 1
+	values := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		// multiplying avoids adding up rounding errors of the step
+		values = append(values, r.Start+float64(i)*r.Step)
+	}
+	return values, nil
+}
+
+// ReadSweep reads a sweep definition and returns the cartesian product of
+// all values. Every parameter either lists its values, or is a range:
+//
+//	parameters:
+//	  foo_int: [1, 2, 3]
+//	  foo_float: {start: 0, stop: 1, step: 0.25}
+func ReadSweep(path string) ([]Row, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sweep file: %w", err)
+	}
+
+	var sweep struct {
+		Parameters map[string]yaml.Node `yaml:"parameters"`
+	}
+	if err := yaml.Unmarshal(buffer, &sweep); err != nil {
+		return nil, fmt.Errorf("failed to load sweep file: %w", err)
+	}
+
+	axes := make(map[string][]interface{})
+	for name, node := range sweep.Parameters {
+		var values []interface{}
+		switch node.Kind {
+		case yaml.SequenceNode:
+			err = node.Decode(&values)
+		case yaml.MappingNode:
+			var r Range
+			if err = node.Decode(&r); err == nil {
+				values, err = r.values()
+			}
+		default:
+			var value interface{}
+			err = node.Decode(&value)
+			values = []interface{}{value}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid sweep of %s: %w", name, err)
+		}
+		axes[name] = values
+	}
+
+	return Cartesian(axes), nil
+}
+
+// Cartesian combines all values of all parameters. The last parameter in
+// alphabetical order changes fastest.
+func Cartesian(axes map[string][]interface{}) []Row {
+	names := make([]string, 0, len(axes))
+	for name := range axes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := []Row{{}}
+	for _, name := range names {
+		next := make([]Row, 0, len(rows)*len(axes[name]))
+		for _, row := range rows {
+			for _, value := range axes[name] {
+				combined := make(Row, len(row)+1)
+				for k, v := range row {
+					combined[k] = v
+				}
+				combined[name] = value
+				next = append(next, combined)
+			}
+		}
+		rows = next
+	}
+	return rows
+}
+
+// ReadRows reads one row per line of a CSV or JSONL file. CSV values are
+// converted to the types of the parameters and empty cells are skipped.
+func ReadRows(path string, spec toolspec.ToolSpec) ([]Row, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return readJSONL(buffer)
+	case ".csv":
+		return readCSV(buffer, spec)
+	}
+	return nil, fmt.Errorf("unknown rows file %s. Use a .csv or .jsonl file", path)
+}
+
+func readJSONL(buffer []byte) ([]Row, error) {
+	rows := make([]Row, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(buffer))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var row Row
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("invalid row in line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func readCSV(buffer []byte, spec toolspec.ToolSpec) ([]Row, error) {
+	records, err := csv.NewReader(bytes.NewReader(buffer)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the rows file has no header")
+	}
+
+	header := records[0]
+	rows := make([]Row, 0, len(records)-1)
+	for line, record := range records[1:] {
+		row := make(Row)
+		for i, cell := range record {
+			if cell == "" {
+				continue
+			}
+			value, err := parseCell(cell, spec.Parameters[header[i]])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s in line %d: %w", header[i], line+2, err)
+			}
+			row[header[i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseCell converts a CSV cell to the type of the parameter. Arrays are
+// given as JSON arrays.
+func parseCell(cell string, param toolspec.ParameterSpec) (interface{}, error) {
+	if param.IsArray {
+		var values []interface{}
+		err := json.Unmarshal([]byte(cell), &values)
+		return values, err
+	}
+
+	switch param.ToolType {
+	case "integer":
+		return strconv.ParseInt(cell, 10, 64)
+	case "float":
+		return strconv.ParseFloat(cell, 64)
+	case "boolean":
+		return strconv.ParseBool(cell)
+	}
+	return cell, nil
+}
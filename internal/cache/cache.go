@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexander-lindner/go-cff"
+	"github.com/hydrocode-de/gotap/internal/checksum"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// outputsDir and entryFile make up a cache entry, which is a folder named
+// by its key
+const (
+	outputsDir = "outputs"
+	entryFile  = "entry.json"
+)
+
+// Entry describes the cached outputs of one run.
+type Entry struct {
+	Key      string    `json:"key"`
+	Tool     string    `json:"tool"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+	Size     int64     `json:"size_bytes"`
+	dir      string
+}
+
+// Key hashes everything that determines the result of a run: the tool's
+// entry in tool.yml, its version and image, the parameters and the SHA-256
+// of every dataset, see checksum.HashDatasets. Missing parameters are
+// hashed with their default, so leaving out a default value hits the same
+// entry as passing it. The code of the tool is not hashed, so changing it
+// needs a new version or image to invalidate the cache.
+func Key(spec toolspec.ToolSpec, version string, image string, input toolspec.ToolInput, data map[string]string) (string, error) {
+	// the citation does not change the result
+	spec.Citation = cff.Cff{}
+
+	parameters := make(map[string]interface{}, len(spec.Parameters))
+	for name, param := range spec.Parameters {
+		if param.Default != nil {
+			parameters[name] = param.Default
+		}
+	}
+	for name, value := range input.Parameters {
+		parameters[name] = value
+	}
+
+	// maps are encoded with sorted keys, which makes the key stable
+	content, err := json.Marshal(map[string]interface{}{
+		"tool":       spec,
+		"version":    version,
+		"image":      image,
+		"parameters": parameters,
+		"data":       data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h, _ := checksum.New(checksum.SHA256)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Lookup returns the entry of a key. Keys can be shortened, as long as they
+// are unique.
+func Lookup(dir string, key string) (Entry, bool, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	var matches []Entry
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Key, key) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) > 1 {
+		return Entry{}, false, fmt.Errorf("the key %s is ambiguous", key)
+	}
+	if len(matches) == 0 {
+		return Entry{}, false, nil
+	}
+	return matches[0], true, nil
+}
+
+func List(dir string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cache: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		entry, err := readEntry(filepath.Join(dir, dirEntry.Name()))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+func readEntry(dir string) (Entry, error) {
+	buffer, err := os.ReadFile(filepath.Join(dir, entryFile))
+	if err != nil {
+		return Entry{}, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(buffer, &entry); err != nil {
+		return Entry{}, err
+	}
+	entry.dir = dir
+	return entry, nil
+}
+
+func writeEntry(entry Entry) error {
+	buffer, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry.dir, entryFile), buffer, 0644)
+}
+
+// Store copies the files of the output folder, which were written by the
+// run, into the cache. The files are relative to outputFolder. The entry is
+// written to a temporary folder first, so that other runs never see a
+// partial entry.
+func Store(dir string, key string, tool string, outputFolder string, files []string) (Entry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Entry{}, fmt.Errorf("failed to create the cache: %w", err)
+	}
+	tmp, err := os.MkdirTemp(dir, ".store-")
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to create the cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var size int64
+	for _, file := range files {
+		target := filepath.Join(tmp, outputsDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return Entry{}, fmt.Errorf("failed to copy the outputs to the cache: %w", err)
+		}
+		n, err := copyFile(filepath.Join(outputFolder, filepath.FromSlash(file)), target)
+		if err != nil {
+			return Entry{}, fmt.Errorf("failed to copy the outputs to the cache: %w", err)
+		}
+		size += n
+	}
+
+	now := time.Now().UTC()
+	entry := Entry{Key: key, Tool: tool, Created: now, LastUsed: now, Size: size, dir: tmp}
+	if err := writeEntry(entry); err != nil {
+		return Entry{}, err
+	}
+
+	entry.dir = filepath.Join(dir, key)
+	os.RemoveAll(entry.dir)
+	if err := os.Rename(tmp, entry.dir); err != nil {
+		return Entry{}, fmt.Errorf("failed to store the cache entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Restore copies the cached outputs into the output folder.
+func Restore(entry Entry, outputFolder string) error {
+	if _, err := copyDir(filepath.Join(entry.dir, outputsDir), outputFolder); err != nil {
+		return fmt.Errorf("failed to restore the outputs from the cache: %w", err)
+	}
+	entry.LastUsed = time.Now().UTC()
+	return writeEntry(entry)
+}
+
+func Remove(entry Entry) error {
+	return os.RemoveAll(entry.dir)
+}
+
+// GC removes entries not used for longer than maxAge, and then the least
+// recently used entries until the cache is smaller than maxSize. Zero
+// values disable the respective check.
+func GC(dir string, maxAge time.Duration, maxSize int64) ([]Entry, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Entry
+	var size int64
+	for _, entry := range entries {
+		expired := maxAge > 0 && time.Since(entry.LastUsed) > maxAge
+		tooLarge := maxSize > 0 && size+entry.Size > maxSize
+		if !expired && !tooLarge {
+			size += entry.Size
+			continue
+		}
+		if err := Remove(entry); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+func copyDir(src string, dst string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		n, err := copyFile(path, target)
+		size += n
+		return err
+	})
+	return size, err
+}
+
+func copyFile(src string, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
@@ -0,0 +1,63 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+
+	"lukechampine.com/blake3"
+)
+
+const (
+	SHA256 = "sha256"
+	BLAKE3 = "blake3"
+	MD5    = "md5"
+)
+
+var algorithms = map[string]func() hash.Hash{
+	SHA256: sha256.New,
+	BLAKE3: func() hash.Hash { return blake3.New(32, nil) },
+	MD5:    md5.New,
+}
+
+// Algorithms lists the names of all supported algorithms.
+func Algorithms() []string {
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func New(algorithm string) (hash.Hash, error) {
+	factory, ok := algorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown checksum algorithm %s. Use one of %v", algorithm, Algorithms())
+	}
+	return factory(), nil
+}
+
+// File returns the hex encoded checksum of the content of a file.
+func File(path string, algorithm string) (string, error) {
+	h, err := New(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
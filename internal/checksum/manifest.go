@@ -0,0 +1,321 @@
+package checksum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestFile lists the checksums of all files in the output folder
+	ManifestFile = "MANIFEST"
+	// InputsManifestFile lists the checksums of all datasets used by a run
+	InputsManifestFile = "MANIFEST.inputs"
+)
+
+// ManifestEntry is one line of a manifest, in the format of sha256sum and
+// similar tools: the checksum, two spaces and the path of the file.
+type ManifestEntry struct {
+	Sum  string
+	Path string
+}
+
+// FolderManifest hashes every file below folder. The paths are relative to
+// the folder and use forward slashes. Files listed in exclude are skipped.
+func FolderManifest(folder string, algorithm string, exclude ...string) ([]ManifestEntry, error) {
+	entries := make([]ManifestEntry, 0)
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, name := range exclude {
+			if rel == name {
+				return nil
+			}
+		}
+
+		sum, err := File(path, algorithm)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, ManifestEntry{Sum: sum, Path: rel})
+		return nil
+	})
+	return entries, err
+}
+
+// Datasets are the checksums of the datasets of a run. Sums holds the
+// SHA-256 of every dataset. For the datasets listed in Dirs, which are
+// directories, it is the SHA-256 of their manifest, so it covers the names
+// and contents of all files. Entries list every file with the manifest
+// algorithm, by the path <name>/<file>.
+type Datasets struct {
+	Sums    map[string]string
+	Dirs    map[string]bool
+	Entries []ManifestEntry
+}
+
+// HashDatasets reads every file of the datasets once, for the SHA-256 and
+// for the manifest algorithm at the same time. paths maps the names of the
+// datasets to their files or directories. If algorithm is none, only the
+// sums are computed.
+func HashDatasets(paths map[string]string, algorithm string) (Datasets, error) {
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	datasets := Datasets{
+		Sums:    make(map[string]string, len(paths)),
+		Dirs:    make(map[string]bool),
+		Entries: make([]ManifestEntry, 0),
+	}
+	for _, name := range names {
+		sum, entries, isDir, err := hashDataset(paths[name], algorithm)
+		if err != nil {
+			return Datasets{}, fmt.Errorf("failed to hash data %s: %w", name, err)
+		}
+		datasets.Sums[name] = sum
+		if isDir {
+			datasets.Dirs[name] = true
+		}
+		for _, entry := range entries {
+			entry.Path = name + "/" + entry.Path
+			datasets.Entries = append(datasets.Entries, entry)
+		}
+	}
+	return datasets, nil
+}
+
+func hashDataset(path string, algorithm string) (string, []ManifestEntry, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if !info.IsDir() {
+		sum, entry, err := hashFile(path, filepath.Base(path), algorithm)
+		return sum, entry, false, err
+	}
+
+	// WalkDir visits the files in lexical order, so the sum of the
+	// directory does not depend on the order they are listed in
+	h := sha256.New()
+	entries := make([]ManifestEntry, 0)
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		sum, entry, err := hashFile(file, filepath.ToSlash(rel), algorithm)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s  %s\n", sum, filepath.ToSlash(rel))
+		entries = append(entries, entry...)
+		return nil
+	})
+	if err != nil {
+		return "", nil, true, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), entries, true, nil
+}
+
+// hashFile returns the SHA-256 of the file and its manifest entry, if the
+// algorithm is not none.
+func hashFile(path string, name string, algorithm string) (string, []ManifestEntry, error) {
+	sha := sha256.New()
+	writers := []io.Writer{sha}
+	var other hash.Hash
+	if algorithm != "none" && algorithm != SHA256 {
+		var err error
+		if other, err = New(algorithm); err != nil {
+			return "", nil, err
+		}
+		writers = append(writers, other)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	if _, err := io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return "", nil, err
+	}
+
+	sum := hex.EncodeToString(sha.Sum(nil))
+	switch {
+	case algorithm == "none":
+		return sum, nil, nil
+	case other != nil:
+		return sum, []ManifestEntry{{Sum: hex.EncodeToString(other.Sum(nil)), Path: name}}, nil
+	}
+	return sum, []ManifestEntry{{Sum: sum, Path: name}}, nil
+}
+
+func WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	for _, entry := range entries {
+		// line breaks in file names are percent-encoded, to keep one
+		// entry per line
+		path := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(entry.Path)
+		if _, err := fmt.Fprintf(w, "%s  %s\n", entry.Sum, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteManifestFile(path string, entries []ManifestEntry) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteManifest(file, entries); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func ReadManifest(r io.Reader) ([]ManifestEntry, error) {
+	entries := make([]ManifestEntry, 0)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		// the path may start with spaces itself
+		sum, path, ok := strings.Cut(text, "  ")
+		if !ok {
+			return nil, fmt.Errorf("invalid manifest line %d", line)
+		}
+		path = strings.NewReplacer("%0A", "\n", "%0D", "\r", "%25", "%").Replace(path)
+		entries = append(entries, ManifestEntry{Sum: strings.ToLower(sum), Path: path})
+	}
+	return entries, scanner.Err()
+}
+
+// Problem is a difference between a folder and its manifest.
+type Problem struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+const (
+	ProblemMissing    = "missing"
+	ProblemChanged    = "changed"
+	ProblemUnexpected = "unexpected"
+)
+
+// VerifyDatasets compares the datasets with their manifest, as written by
+// HashDatasets. paths maps the names of the datasets to their files or
+// directories. The files of datasets, which do not exist any more, are
+// reported as missing.
+func VerifyDatasets(paths map[string]string, manifest []ManifestEntry, algorithm string) ([]Problem, error) {
+	existing := make(map[string]string, len(paths))
+	for name, path := range paths {
+		_, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing[name] = path
+	}
+
+	datasets, err := HashDatasets(existing, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]Problem, 0)
+	actual := make(map[string]string, len(datasets.Entries))
+	for _, entry := range datasets.Entries {
+		actual[entry.Path] = entry.Sum
+	}
+	listed := make(map[string]bool, len(manifest))
+	for _, entry := range manifest {
+		listed[entry.Path] = true
+		sum, ok := actual[entry.Path]
+		if !ok {
+			problems = append(problems, Problem{Path: entry.Path, Kind: ProblemMissing, Expected: entry.Sum})
+		} else if sum != entry.Sum {
+			problems = append(problems, Problem{Path: entry.Path, Kind: ProblemChanged, Expected: entry.Sum, Actual: sum})
+		}
+	}
+	for _, entry := range datasets.Entries {
+		if !listed[entry.Path] {
+			problems = append(problems, Problem{Path: entry.Path, Kind: ProblemUnexpected})
+		}
+	}
+	return problems, nil
+}
+
+// VerifyFolder compares the files below folder with the manifest. Files not
+// listed in the manifest are reported as unexpected, except for the ones
+// in exclude.
+func VerifyFolder(folder string, manifest []ManifestEntry, algorithm string, exclude ...string) ([]Problem, error) {
+	problems := make([]Problem, 0)
+	listed := make(map[string]bool, len(manifest))
+
+	for _, entry := range manifest {
+		listed[entry.Path] = true
+		sum, err := File(filepath.Join(folder, filepath.FromSlash(entry.Path)), algorithm)
+		if os.IsNotExist(err) {
+			problems = append(problems, Problem{Path: entry.Path, Kind: ProblemMissing, Expected: entry.Sum})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sum != entry.Sum {
+			problems = append(problems, Problem{Path: entry.Path, Kind: ProblemChanged, Expected: entry.Sum, Actual: sum})
+		}
+	}
+
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, name := range exclude {
+			if rel == name {
+				return nil
+			}
+		}
+		if !listed[rel] {
+			problems = append(problems, Problem{Path: rel, Kind: ProblemUnexpected})
+		}
+		return nil
+	})
+	return problems, err
+}
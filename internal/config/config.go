@@ -23,4 +23,34 @@ func setupDefaults() {
 	v.SetDefault("citation_file", "CITATION.cff")
 	v.SetDefault("license_file", "LICENSE")
 	v.SetDefault("output_folder", "../out")
+	v.SetDefault("timeout", "")
+	v.SetDefault("grace_period", "10s")
+	v.SetDefault("fail_on_warnings", false)
+	v.SetDefault("output_buffer_size", 1024*1024)
+	v.SetDefault("timestamps", false)
+	v.SetDefault("stream_names", false)
+	v.SetDefault("sample_interval", "100ms")
+	v.SetDefault("resource_log", "")
+	v.SetDefault("memory_limit", "")
+	v.SetDefault("cpu_time_limit", "")
+	v.SetDefault("open_files_limit", 0)
+	v.SetDefault("process_limit", 0)
+	v.SetDefault("ro_crate", false)
+	v.SetDefault("docker_image", "")
+	v.SetDefault("addr", "127.0.0.1:8080")
+	v.SetDefault("jobs_dir", "")
+	v.SetDefault("max_jobs", 1)
+	v.SetDefault("job_ttl", "24h")
+	v.SetDefault("max_queued_jobs", 100)
+	v.SetDefault("max_upload_size", "1GiB")
+	v.SetDefault("cache_dir", "")
+	v.SetDefault("provenance", true)
+	v.SetDefault("image_digest", "")
+	v.SetDefault("checksum", "sha256")
+	v.SetDefault("input_env", false)
+	v.SetDefault("env_param_prefix", "TAP_PARAM_")
+	v.SetDefault("env_data_prefix", "TAP_DATA_")
+	v.SetDefault("env_name_case", "upper")
+	v.SetDefault("env_array_format", "json")
+	v.SetDefault("env_array_delimiter", ",")
 }
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration accepts Go duration strings like 90m or 1h30m, and days
+// like 30d. Plain numbers are interpreted as seconds. Negative durations
+// are rejected.
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("invalid duration %s: the duration is negative", value)
+	}
+	return duration, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := parseNumber(value); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if number, err := parseNumber(days); err == nil {
+			return time.Duration(number * float64(24*time.Hour)), nil
+		}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s: %w", value, err)
+	}
+	return duration, nil
+}
+
+// parseNumber is strconv.ParseFloat without NaN and infinity.
+func parseNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%s is not a finite number", value)
+	}
+	return number, nil
+}
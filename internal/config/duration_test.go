@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "90", want: 90 * time.Second},
+		{value: "1.5", want: 1500 * time.Millisecond},
+		{value: "1e3", want: 1000 * time.Second},
+		{value: "90m", want: 90 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "30d", want: 30 * 24 * time.Hour},
+		{value: "1.5d", want: 36 * time.Hour},
+		{value: "0", want: 0},
+		{value: "-5", wantErr: true},
+		{value: "-1d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "5 ", wantErr: true},
+		{value: "5x", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDuration(test.value)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDuration(%q) failed: %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+var sizeUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseSize parses byte sizes like 512M or 2GiB, using binary units.
+// Plain numbers are interpreted as bytes.
+func ParseSize(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	unit := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	// the i of KiB and friends is only allowed after a unit
+	if prefix, ok := strings.CutSuffix(unit, "I"); ok && strings.ContainsAny(prefix[max(len(prefix)-1, 0):], "KMGT") {
+		unit = prefix
+	}
+	number := strings.TrimRight(unit, "KMGT")
+	unit = unit[len(number):]
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %s", value)
+	}
+	size, err := parseNumber(strings.TrimSpace(number))
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %s", value)
+	}
+
+	return uint64(size * float64(multiplier)), nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "1024", want: 1024},
+		{value: "512M", want: 512 << 20},
+		{value: "512m", want: 512 << 20},
+		{value: "2G", want: 2 << 30},
+		{value: "2GB", want: 2 << 30},
+		{value: "2GiB", want: 2 << 30},
+		{value: "2gib", want: 2 << 30},
+		{value: "1.5K", want: 1536},
+		{value: "1 T", want: 1 << 40},
+		{value: " 64K ", want: 64 << 10},
+		{value: "100B", want: 100},
+		{value: "-1", wantErr: true},
+		{value: "-1M", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+		{value: "2iB", wantErr: true},
+		{value: "5KM", wantErr: true},
+		{value: "5P", wantErr: true},
+		{value: "K", wantErr: true},
+		{value: "B", wantErr: true},
+		{value: "lots", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSize(test.value)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package config
+
+// Version of gotap. Release builds set it using
+// -ldflags "-X github.com/hydrocode-de/gotap/internal/config.Version=v1.2.3"
+var Version = "dev"
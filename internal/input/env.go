@@ -0,0 +1,123 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// EnvOptions control how parameters and datasets are exported as
+// environment variables. The variable of a parameter foo_int is named
+// TAP_PARAM_FOO_INT by default.
+type EnvOptions struct {
+	Enabled     bool
+	ParamPrefix string
+	DataPrefix  string
+	// NameCase is upper, lower or keep
+	NameCase string
+	// ArrayFormat is json or join. Joined arrays use the ArrayDelimiter
+	ArrayFormat    string
+	ArrayDelimiter string
+}
+
+func (o EnvOptions) Validate() error {
+	switch o.NameCase {
+	case "upper", "lower", "keep":
+	default:
+		return fmt.Errorf("unknown name case %s. Use upper, lower or keep", o.NameCase)
+	}
+	switch o.ArrayFormat {
+	case "json", "join":
+	default:
+		return fmt.Errorf("unknown array format %s. Use json or join", o.ArrayFormat)
+	}
+	return nil
+}
+
+func (o EnvOptions) name(prefix string, name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	switch o.NameCase {
+	case "upper":
+		name = strings.ToUpper(name)
+	case "lower":
+		name = strings.ToLower(name)
+	}
+	return prefix + name
+}
+
+func (o EnvOptions) value(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case []interface{}:
+		if o.ArrayFormat == "join" {
+			values := make([]string, 0, len(typed))
+			for _, v := range typed {
+				values = append(values, o.value(v))
+			}
+			return strings.Join(values, o.ArrayDelimiter)
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// Variables returns the environment variables of all parameters, including
+// the defaults from tool.yml, and the resolved paths of all datasets.
+func (o EnvOptions) Variables(spec toolspec.ToolSpec, input toolspec.ToolInput, inputFile string) []string {
+	if !o.Enabled {
+		return nil
+	}
+
+	parameters := make(map[string]interface{})
+	for name, param := range spec.Parameters {
+		if param.Default != nil {
+			parameters[name] = param.Default
+		}
+	}
+	for name, value := range input.Parameters {
+		parameters[name] = value
+	}
+
+	env := make([]string, 0, len(parameters)+len(input.Datasets))
+	for name, value := range parameters {
+		if value == nil {
+			continue
+		}
+		env = append(env, o.name(o.ParamPrefix, name)+"="+o.value(value))
+	}
+	for name, dataPath := range input.Datasets {
+		path := io.ResolveDataPath(dataPath, inputFile)
+		if absolute, err := filepath.Abs(path); err == nil {
+			path = absolute
+		}
+		env = append(env, o.name(o.DataPrefix, name)+"="+path)
+	}
+
+	sort.Strings(env)
+	return env
+}
+
+// ExportInputs adds the variables of the inputs to the environment of the
+// tool, if this is enabled.
+func (o *ExecutionOptions) ExportInputs(spec toolspec.ToolSpec, input toolspec.ToolInput, inputFile string) {
+	o.Env = append(o.Env, o.InputEnv.Variables(spec, input, inputFile)...)
+}
@@ -1,16 +1,21 @@
 package input
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/hydrocode-de/gotap/internal/config"
 	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
@@ -20,18 +25,95 @@ type ResolvedCommand struct {
 	Extension  string
 }
 
+// ExecutionOptions control how a resolved command is executed. The zero
+// value runs the command without any deadline.
+type ExecutionOptions struct {
+	Timeout     time.Duration
+	GracePeriod time.Duration
+
+	// Stdout and Stderr receive the output of the tool while it runs.
+	// The result only keeps the last OutputBufferSize bytes of each.
+	Stdout           io.Writer
+	Stderr           io.Writer
+	OutputBufferSize int
+	Timestamps       bool
+	StreamNames      bool
+
+	// SampleInterval sets how often the resource usage is sampled. Each
+	// sample is passed to OnSample, if set.
+	SampleInterval time.Duration
+	OnSample       func(ResourceSample)
+
+	Limits ResourceLimits
+
+	// Env is added to the environment gotap was started with
+	Env []string
+	// InputEnv configures the export of the inputs, see ExportInputs
+	InputEnv EnvOptions
+
+	// Binds are only visible to the tool, see Sandbox
+	Binds []Bind
+}
+
+// TerminationReason tells why the tool process ended.
+type TerminationReason string
+
+const (
+	TerminationExited  TerminationReason = "exited"
+	TerminationTimeout TerminationReason = "timeout"
+	TerminationSignal  TerminationReason = "signal"
+	TerminationLimit   TerminationReason = "limit"
+)
+
 type ExecutionResult struct {
-	Stdout        []byte        `json:"-"`
-	Stderr        []byte        `json:"-"`
-	ExitCode      int           `json:"exit_code"`
-	UserTime      time.Duration `json:"user_time"`
-	SystemTime    time.Duration `json:"system_time"`
-	MemoryMax     uint64        `json:"memory_max_bytes"`
-	MemoryAverage uint64        `json:"memory_average_bytes"`
-	CPUMax        uint64        `json:"cpu_max_permille"`
-	CPUAverage    uint64        `json:"cpu_average_permille"`
-	ReadBytesSum  uint64        `json:"read_bytes_sum"`
-	WriteBytesSum uint64        `json:"write_bytes_sum"`
+	Stdout        []byte            `json:"-"`
+	Stderr        []byte            `json:"-"`
+	ExitCode      int               `json:"exit_code"`
+	Termination   TerminationReason `json:"termination_reason"`
+	Signal        string            `json:"signal,omitempty"`
+	LimitExceeded string            `json:"limit_exceeded,omitempty"`
+	LimitsBackend string            `json:"limits_backend,omitempty"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	WallTime      float64           `json:"wall_time_seconds"`
+	UserTime      float64           `json:"user_time_seconds"`
+	SystemTime    float64           `json:"system_time_seconds"`
+	MemoryMax     uint64            `json:"memory_max_bytes"`
+	MemoryAverage uint64            `json:"memory_average_bytes"`
+	CPUMax        uint64            `json:"cpu_max_permille"`
+	CPUAverage    uint64            `json:"cpu_average_permille"`
+	ReadBytesSum  uint64            `json:"read_bytes_sum"`
+	WriteBytesSum uint64            `json:"write_bytes_sum"`
+	ProcessMax    int               `json:"process_count_max"`
+	Executables   []ExecutableUsage `json:"executables,omitempty"`
+	Command       string            `json:"command"`
+	Host          HostInfo          `json:"host"`
+	GotapVersion  string            `json:"gotap_version"`
+	CacheHit      bool              `json:"cache_hit"`
+	CacheKey      string            `json:"cache_key,omitempty"`
+
+	ManifestAlgorithm string                      `json:"manifest_algorithm,omitempty"`
+	OutputErrors      []*validate.ValidationError `json:"output_errors,omitempty"`
+	// StdoutTruncated and StderrTruncated tell if Stdout and Stderr only
+	// hold the end of the output, see OutputBufferSize
+	StdoutTruncated bool `json:"stdout_truncated"`
+	StderrTruncated bool `json:"stderr_truncated"`
+}
+
+// HostInfo describes the machine the tool ran on.
+type HostInfo struct {
+	Hostname    string `json:"hostname"`
+	CPUCount    int    `json:"cpu_count"`
+	MemoryTotal uint64 `json:"memory_total_bytes"`
+}
+
+func currentHost() HostInfo {
+	host := HostInfo{CPUCount: runtime.NumCPU()}
+	host.Hostname, _ = os.Hostname()
+	if memory, err := mem.VirtualMemory(); err == nil {
+		host.MemoryTotal = memory.Total
+	}
+	return host
 }
 
 func isExecutable(path string) bool {
@@ -89,7 +171,7 @@ func parseMatch(match string) (ResolvedCommand, error) {
 		executable = "sh"
 	case ".py":
 		executable = "python3"
-	case ".R":
+	case ".r":
 		executable = "Rscript"
 	case ".jl":
 		executable = "julia"
@@ -171,14 +253,44 @@ func ResolveCommand(spec toolspec.ToolSpec) (ResolvedCommand, error) {
 	return ResolvedCommand{}, fmt.Errorf("the command could not be found. Consider adding it to your tool.yml")
 }
 
-func ExecuteCommand(command ResolvedCommand) (ExecutionResult, error) {
+func newCommand(command ResolvedCommand, opts ExecutionOptions, stdout, stderr io.Writer) *exec.Cmd {
 	cmd := exec.Command("sh", "-c", command.Command)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = opts.GracePeriod
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if opts.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(stdout, newLinePrefixWriter(opts.Stdout, "stdout", opts))
+	}
+	if opts.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, newLinePrefixWriter(opts.Stderr, "stderr", opts))
+	}
+
+	return cmd
+}
+
+func ExecuteCommand(command ResolvedCommand, opts ExecutionOptions) (ExecutionResult, error) {
+	stdout := &CappedBuffer{Limit: opts.OutputBufferSize}
+	stderr := &CappedBuffer{Limit: opts.OutputBufferSize}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	limits := newLimiter(opts.Limits)
+	defer limits.cleanup()
 
+	cmd := newCommand(command, opts, stdout, stderr)
+	limits.prepare(cmd, opts.Binds)
+	startTime := time.Now()
 	err := cmd.Start()
+	if err != nil && limits.usesCgroup() {
+		// the cgroup could be created, but not joined. Fall back to rlimits
+		limits.dropCgroup()
+		cmd = newCommand(command, opts, stdout, stderr)
+		limits.prepare(cmd, opts.Binds)
+		err = cmd.Start()
+	}
 	if err != nil {
 		return ExecutionResult{}, fmt.Errorf("failed to execute command: %w", err)
 	}
@@ -189,11 +301,18 @@ func ExecuteCommand(command ResolvedCommand) (ExecutionResult, error) {
 		return ExecutionResult{}, fmt.Errorf("failed to create process: %w", err)
 	}
 
+	tree := newProcessTree(proc)
+	tree.trackFDs = opts.Limits.OpenFiles > 0
 	var memSamples []uint64
 	var cpuSamples []uint64
 
-	ticker := time.NewTicker(100 * time.Millisecond)
+	sampleInterval := opts.SampleInterval
+	if sampleInterval <= 0 {
+		sampleInterval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(sampleInterval)
 	defer ticker.Stop()
+	var lastSample ResourceSample
 
 	// this actually runs in a goroutine and waits for the command to finish
 	done := make(chan bool)
@@ -202,44 +321,106 @@ func ExecuteCommand(command ResolvedCommand) (ExecutionResult, error) {
 		close(done)
 	}()
 
+	// termination signals sent to gotap are forwarded to the process group,
+	// as the tool does not see them otherwise when gotap runs as PID 1
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	// on timeout, the process group gets a SIGTERM and is killed if it
+	// did not finish within the grace period
+	var timeout, kill <-chan time.Time
+	if opts.Timeout > 0 {
+		timeoutTimer := time.NewTimer(opts.Timeout)
+		defer timeoutTimer.Stop()
+		timeout = timeoutTimer.C
+	}
+	termination := TerminationExited
+	killedByGotap := false
+	var endTime time.Time
+
 	sampling := true
 	for sampling {
 		select {
 		case <-done:
+			endTime = time.Now()
 			ticker.Stop()
 			sampling = false
-		case <-ticker.C:
-			mem, err := proc.MemoryInfo()
-			if err == nil {
-				memSamples = append(memSamples, mem.RSS)
+		case <-timeout:
+			termination = TerminationTimeout
+			signalProcessGroup(cmd, syscall.SIGTERM)
+			if kill == nil {
+				killTimer := time.NewTimer(opts.GracePeriod)
+				defer killTimer.Stop()
+				kill = killTimer.C
+			}
+		case sig := <-signals:
+			if termination == TerminationExited {
+				termination = TerminationSignal
+			}
+			signalProcessGroup(cmd, sig.(syscall.Signal))
+			if kill == nil {
+				killTimer := time.NewTimer(opts.GracePeriod)
+				defer killTimer.Stop()
+				kill = killTimer.C
 			}
-			cpu, err := proc.CPUPercent()
-			if err == nil {
-				cpuSamples = append(cpuSamples, uint64(cpu*1000))
+		case <-kill:
+			killedByGotap = true
+			signalProcessGroup(cmd, syscall.SIGKILL)
+		case now := <-ticker.C:
+			sample := tree.sample(now)
+			memSamples = append(memSamples, sample.RSS)
+			cpuSamples = append(cpuSamples, sample.CPU)
+			lastSample = sample
+			if opts.OnSample != nil {
+				opts.OnSample(sample)
 			}
 		}
 	}
 
-	var readBytesSum, writeBytesSum uint64
-	ioCounters, err := proc.IOCounters()
-	if err == nil {
-		readBytesSum = ioCounters.ReadBytes
-		writeBytesSum = ioCounters.WriteBytes
-	}
+	// the processes are gone by now, so the io counters of the last sample
+	// are the best we have
+	readBytesSum, writeBytesSum := lastSample.ReadBytes, lastSample.WriteBytes
 
+	// a tool ended by a signal reports the exit code a shell would use
 	exitCode := cmd.ProcessState.ExitCode()
+	var exitSignal string
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		exitCode = 128 + int(status.Signal())
+		exitSignal = status.Signal().String()
+	}
+
+	limitExceeded := limits.exceeded(cmd.ProcessState, killedByGotap, tree, stderr.Bytes())
+	if limitExceeded != "" && termination == TerminationExited {
+		termination = TerminationLimit
+	}
 	return ExecutionResult{
 		Stdout:        stdout.Bytes(),
 		Stderr:        stderr.Bytes(),
 		ExitCode:      exitCode,
-		UserTime:      cmd.ProcessState.UserTime(),
-		SystemTime:    cmd.ProcessState.SystemTime(),
+		Termination:   termination,
+		Signal:        exitSignal,
+		LimitExceeded: limitExceeded,
+		LimitsBackend: limits.backend,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		WallTime:      endTime.Sub(startTime).Seconds(),
+		UserTime:      cmd.ProcessState.UserTime().Seconds(),
+		SystemTime:    cmd.ProcessState.SystemTime().Seconds(),
 		MemoryMax:     calcualateMax(memSamples),
 		MemoryAverage: calcualteAverage(memSamples),
 		CPUMax:        calcualateMax(cpuSamples),
 		CPUAverage:    calcualteAverage(cpuSamples),
 		ReadBytesSum:  readBytesSum,
 		WriteBytesSum: writeBytesSum,
+		ProcessMax:    tree.processMax,
+		Executables:   tree.executables(),
+		Command:       command.Command,
+		Host:          currentHost(),
+		GotapVersion:  config.Version,
+
+		StdoutTruncated: stdout.Truncated,
+		StderrTruncated: stderr.Truncated,
 	}, nil
 }
 
@@ -0,0 +1,97 @@
+package input
+
+import (
+	"bytes"
+	"os"
+	"time"
+)
+
+// ResourceLimits are enforced on the tool process. Zero values are not
+// limited. Without a cgroup, the process limit falls back to RLIMIT_NPROC,
+// which counts all processes of the user and does not apply to root.
+type ResourceLimits struct {
+	Memory    uint64
+	CPUTime   time.Duration
+	OpenFiles uint64
+	Processes uint64
+}
+
+func (l ResourceLimits) IsSet() bool {
+	return l.Memory > 0 || l.CPUTime > 0 || l.OpenFiles > 0 || l.Processes > 0
+}
+
+// LimitedExecCommand is the hidden command gotap re-executes itself with,
+// to set the rlimits and bind mounts before the tool is started.
+const LimitedExecCommand = "__exec-limited"
+
+const (
+	LimitMemory    = "memory"
+	LimitCPUTime   = "cpu_time"
+	LimitOpenFiles = "open_files"
+	LimitProcesses = "processes"
+)
+
+// limiter applies the limits to the tool process. On linux, a cgroup v2
+// sub-group is used for memory and processes, if the memory and pids
+// controllers are delegated to the cgroup of gotap. Otherwise, everything
+// is enforced with rlimits.
+type limiter struct {
+	limits     ResourceLimits
+	backend    string
+	cgroup     string
+	cgroupFile *os.File
+}
+
+// limitMessages are error messages printed by common runtimes, when they
+// fail because of an rlimit. As the kernel does not report the limit, they
+// are used as a hint, if the tool failed.
+var limitMessages = map[string][]string{
+	LimitMemory:    {"Cannot allocate memory", "MemoryError", "cannot allocate vector", "out of memory", "bad_alloc"},
+	LimitOpenFiles: {"Too many open files"},
+	LimitProcesses: {"Cannot fork", "can't fork", "fork: Resource temporarily unavailable", "fork: retry"},
+}
+
+// exceeded figures out which limit, if any, ended the tool. The kernel does
+// not report this directly, so the cgroup events, the signal, the sampled
+// process tree and finally the error output are taken into account.
+func (l *limiter) exceeded(state *os.ProcessState, killedByGotap bool, tree *processTree, stderr []byte) string {
+	// a tool, which finished successfully, was not stopped by a limit,
+	// even if it came close to one
+	if !l.limits.IsSet() || state.Success() {
+		return ""
+	}
+
+	if limit := l.exceededBySignal(state, killedByGotap); limit != "" {
+		return limit
+	}
+	if l.limits.Memory > 0 && l.cgroupEvent("memory.events", "oom_kill") > 0 {
+		return LimitMemory
+	}
+	if l.limits.Processes > 0 && l.cgroupEvent("pids.events", "max") > 0 {
+		return LimitProcesses
+	}
+	if l.limits.Processes > 0 && uint64(tree.processMax) >= l.limits.Processes {
+		return LimitProcesses
+	}
+	if l.limits.OpenFiles > 0 && uint64(tree.fdMax) >= l.limits.OpenFiles {
+		return LimitOpenFiles
+	}
+
+	limitSet := map[string]bool{
+		LimitMemory:    l.limits.Memory > 0,
+		LimitOpenFiles: l.limits.OpenFiles > 0,
+		LimitProcesses: l.limits.Processes > 0,
+	}
+	for limit, messages := range limitMessages {
+		if !limitSet[limit] {
+			continue
+		}
+		for _, message := range messages {
+			if bytes.Contains(stderr, []byte(message)) {
+				return limit
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,320 @@
+//go:build linux
+
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func newLimiter(limits ResourceLimits) *limiter {
+	l := &limiter{limits: limits}
+	if !limits.IsSet() {
+		return l
+	}
+
+	l.backend = "rlimit"
+	if limits.Memory > 0 || limits.Processes > 0 {
+		if err := l.createCgroup(); err == nil {
+			l.backend = "cgroup"
+		}
+	}
+	return l
+}
+
+// cgroupRoot returns the mount point of the cgroup v2 hierarchy, which is
+// /sys/fs/cgroup on unified systems and usually /sys/fs/cgroup/unified on
+// hybrid ones.
+func cgroupRoot() (string, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		mount, fsInfo, ok := strings.Cut(scanner.Text(), " - ")
+		fields := strings.Fields(mount)
+		if ok && len(fields) > 4 && strings.HasPrefix(fsInfo, "cgroup2 ") {
+			return fields[4], nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 hierarchy mounted")
+}
+
+// ownCgroup returns the cgroup v2 directory gotap itself is running in.
+func ownCgroup() (string, error) {
+	root, err := cgroupRoot()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return filepath.Join(root, path), nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 hierarchy found")
+}
+
+// delegated checks that the controllers are enabled for the sub-groups of
+// parent. gotap never enables them itself, as this would change the cgroup
+// tree of the host or container, and nothing could undo it if gotap was
+// killed. Without them, the limits fall back to rlimits.
+func delegated(parent string, controllers []string) error {
+	enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	for _, controller := range controllers {
+		if !slices.Contains(strings.Fields(string(enabled)), controller) {
+			return fmt.Errorf("the %s controller is not delegated to %s", controller, parent)
+		}
+	}
+	return nil
+}
+
+func (l *limiter) createCgroup() error {
+	parent, err := ownCgroup()
+	if err != nil {
+		return err
+	}
+
+	var controllers []string
+	if l.limits.Memory > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if l.limits.Processes > 0 {
+		controllers = append(controllers, "pids")
+	}
+	if err := delegated(parent, controllers); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp(parent, "gotap-")
+	if err != nil {
+		return err
+	}
+	l.cgroup = dir
+
+	if l.limits.Memory > 0 {
+		err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatUint(l.limits.Memory, 10)), 0644)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		}
+	}
+	if err == nil && l.limits.Processes > 0 {
+		err = os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.FormatUint(l.limits.Processes, 10)), 0644)
+	}
+	if err == nil {
+		l.cgroupFile, err = os.Open(dir)
+	}
+	if err != nil {
+		l.dropCgroup()
+		return err
+	}
+
+	return nil
+}
+
+func (l *limiter) dropCgroup() {
+	if l.cgroupFile != nil {
+		l.cgroupFile.Close()
+		l.cgroupFile = nil
+	}
+	if l.cgroup != "" {
+		os.Remove(l.cgroup)
+		l.cgroup = ""
+	}
+	if l.backend == "cgroup" {
+		l.backend = "rlimit"
+	}
+}
+
+// prepare places the command into the cgroup as it is started, so that no
+// child can escape it. The rlimits have to be set before the tool is
+// started as well, hence gotap re-executes itself to set them and then
+// replaces itself with the tool.
+func (l *limiter) prepare(cmd *exec.Cmd, binds []Bind) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if l.cgroupFile != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(l.cgroupFile.Fd())
+	}
+	// the bind mounts are done in a private mount namespace of the tool
+	if len(binds) > 0 {
+		cmd.SysProcAttr.Unshareflags |= syscall.CLONE_NEWNS
+	}
+
+	rlimits := l.rlimits()
+	if len(rlimits) == 0 && len(binds) == 0 {
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	args := []string{self, LimitedExecCommand}
+	for resource, value := range rlimits {
+		args = append(args, fmt.Sprintf("%d=%d", resource, value))
+	}
+	for _, bind := range binds {
+		args = append(args, fmt.Sprintf("bind:%s=%s", bind.Target, bind.Source))
+	}
+	args = append(args, "--")
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Path = self
+}
+
+func (l *limiter) usesCgroup() bool {
+	return l.cgroupFile != nil
+}
+
+// rlimits returns the limits, which are not covered by the cgroup.
+func (l *limiter) rlimits() map[int]uint64 {
+	rlimits := make(map[int]uint64)
+	if l.limits.CPUTime > 0 {
+		rlimits[unix.RLIMIT_CPU] = max(uint64(l.limits.CPUTime.Seconds()), 1)
+	}
+	if l.limits.OpenFiles > 0 {
+		rlimits[unix.RLIMIT_NOFILE] = l.limits.OpenFiles
+	}
+	if l.cgroupFile == nil && l.limits.Memory > 0 {
+		rlimits[unix.RLIMIT_AS] = l.limits.Memory
+	}
+	// RLIMIT_NPROC counts all processes of the user, not only those of the
+	// tool, and is not enforced for root at all. Hence the process limit
+	// only works reliably with a cgroup.
+	if l.cgroupFile == nil && l.limits.Processes > 0 {
+		rlimits[unix.RLIMIT_NPROC] = l.limits.Processes
+	}
+	return rlimits
+}
+
+// ExecLimited sets the rlimits given as resource=value pairs, bind mounts
+// the bind:target=source pairs and replaces the current process with the
+// command following the -- separator. The soft cpu limit sends SIGXCPU, the
+// hard limit one second later kills the tool.
+func ExecLimited(args []string) error {
+	for i, arg := range args {
+		if arg == "--" {
+			if i+1 >= len(args) {
+				break
+			}
+			path, err := exec.LookPath(args[i+1])
+			if err != nil {
+				return err
+			}
+			return syscall.Exec(path, args[i+1:], os.Environ())
+		}
+
+		if bind, ok := strings.CutPrefix(arg, "bind:"); ok {
+			target, source, ok := strings.Cut(bind, "=")
+			if !ok {
+				return fmt.Errorf("invalid bind mount %s", arg)
+			}
+			if err := unix.Mount(source, target, "", unix.MS_BIND, ""); err != nil {
+				return fmt.Errorf("failed to mount %s at %s: %w", source, target, err)
+			}
+			continue
+		}
+
+		resourceValue, limitValue, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid limit %s", arg)
+		}
+		resource, err := strconv.Atoi(resourceValue)
+		if err != nil {
+			return fmt.Errorf("invalid limit %s", arg)
+		}
+		limit, err := strconv.ParseUint(limitValue, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid limit %s", arg)
+		}
+
+		rlimit := unix.Rlimit{Cur: limit, Max: limit}
+		if resource == unix.RLIMIT_CPU {
+			rlimit.Max = limit + 1
+		}
+		if err := unix.Setrlimit(resource, &rlimit); err != nil {
+			return fmt.Errorf("failed to set limit %s: %w", arg, err)
+		}
+	}
+
+	return fmt.Errorf("no command given")
+}
+
+func (l *limiter) exceededBySignal(state *os.ProcessState, killedByGotap bool) string {
+	// the signal either ended the sh wrapper itself, or sh reports the
+	// signal that ended the tool in its exit code
+	var sig syscall.Signal
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		sig = status.Signal()
+	} else if code := state.ExitCode(); code > 128 && code < 128+65 {
+		sig = syscall.Signal(code - 128)
+	}
+
+	switch sig {
+	case syscall.SIGXCPU:
+		return LimitCPUTime
+	case syscall.SIGKILL:
+		if killedByGotap {
+			return ""
+		}
+		if l.limits.CPUTime > 0 && state.UserTime()+state.SystemTime() >= l.limits.CPUTime {
+			return LimitCPUTime
+		}
+		// without a cgroup, an unexpected SIGKILL is most likely the OOM killer
+		if l.limits.Memory > 0 && l.cgroup == "" {
+			return LimitMemory
+		}
+	}
+	return ""
+}
+
+func (l *limiter) cgroupEvent(file string, key string) uint64 {
+	if l.cgroup == "" {
+		return 0
+	}
+
+	content, err := os.ReadFile(filepath.Join(l.cgroup, file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			count, _ := strconv.ParseUint(fields[1], 10, 64)
+			return count
+		}
+	}
+	return 0
+}
+
+func (l *limiter) cleanup() {
+	l.dropCgroup()
+}
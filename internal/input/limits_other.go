@@ -0,0 +1,37 @@
+//go:build !linux
+
+package input
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// resource limits are only enforced on linux. Elsewhere, the limits are
+// only used to interpret the sampled process tree.
+func newLimiter(limits ResourceLimits) *limiter {
+	return &limiter{limits: limits}
+}
+
+func (l *limiter) prepare(cmd *exec.Cmd, binds []Bind) {}
+
+func (l *limiter) usesCgroup() bool {
+	return false
+}
+
+func (l *limiter) dropCgroup() {}
+
+func ExecLimited(args []string) error {
+	return fmt.Errorf("resource limits are only supported on linux")
+}
+
+func (l *limiter) exceededBySignal(state *os.ProcessState, killedByGotap bool) string {
+	return ""
+}
+
+func (l *limiter) cgroupEvent(file string, key string) uint64 {
+	return 0
+}
+
+func (l *limiter) cleanup() {}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package input
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// process groups are not available on windows, the signals are sent to
+// the direct child only.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
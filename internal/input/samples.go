@@ -0,0 +1,63 @@
+package input
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ResourceSample is a single measurement of the resources used by the tool.
+type ResourceSample struct {
+	Timestamp  time.Time `json:"timestamp"`
+	RSS        uint64    `json:"rss_bytes"`
+	CPU        uint64    `json:"cpu_permille"`
+	ReadBytes  uint64    `json:"read_bytes"`
+	WriteBytes uint64    `json:"write_bytes"`
+	Processes  int       `json:"processes"`
+}
+
+// SampleWriter writes resource samples as CSV or JSON lines.
+type SampleWriter struct {
+	format string
+	csv    *csv.Writer
+	json   *json.Encoder
+}
+
+func NewSampleWriter(w io.Writer, format string) (*SampleWriter, error) {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"timestamp", "rss_bytes", "cpu_permille", "read_bytes", "write_bytes", "processes"})
+		// the header is written right away, in case the tool exits before
+		// the first sample
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, err
+		}
+		return &SampleWriter{format: format, csv: writer}, nil
+	case "jsonl":
+		return &SampleWriter{format: format, json: json.NewEncoder(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown resource log format %s. Use csv or jsonl", format)
+}
+
+func (s *SampleWriter) Write(sample ResourceSample) error {
+	if s.format == "jsonl" {
+		return s.json.Encode(sample)
+	}
+
+	s.csv.Write([]string{
+		sample.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatUint(sample.RSS, 10),
+		strconv.FormatUint(sample.CPU, 10),
+		strconv.FormatUint(sample.ReadBytes, 10),
+		strconv.FormatUint(sample.WriteBytes, 10),
+		strconv.Itoa(sample.Processes),
+	})
+	s.csv.Flush()
+	return s.csv.Error()
+}
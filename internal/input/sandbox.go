@@ -0,0 +1,76 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Bind mounts Source at Target for the tool only.
+type Bind struct {
+	Source string
+	Target string
+}
+
+// Sandbox gives every run of a tool its own inputs.json and output folder
+// at the locations the tool reads and writes, usually /in/inputs.json and
+// /out. Both are bind mounted in a mount namespace of the tool, so runs
+// can overlap and nothing already in the output folder is touched.
+type Sandbox struct {
+	InputFile    string
+	OutputFolder string
+}
+
+// NewSandbox fails, if gotap may not create mount namespaces. Staging the
+// files of each run into the shared locations instead could not tell the
+// outputs of the run from files, which were there before.
+func NewSandbox(inputFile string, outputFolder string) (*Sandbox, error) {
+	if !canMount() {
+		return nil, fmt.Errorf("gotap cannot create mount namespaces here, which are needed to give every run its own inputs.json and output folder. Run it as root, or with CAP_SYS_ADMIN inside a container")
+	}
+
+	inputFile, err := filepath.Abs(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	outputFolder, err = filepath.Abs(outputFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sandbox{
+		InputFile:    inputFile,
+		OutputFolder: outputFolder,
+	}, nil
+}
+
+// Enter prepares a run, which reads inputFile and writes to outputFolder,
+// by adding the bind mounts to opts.
+func (s *Sandbox) Enter(opts *ExecutionOptions, inputFile string, outputFolder string) error {
+	// the mount points need to exist
+	if err := touch(s.InputFile); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.OutputFolder, 0755); err != nil {
+		return err
+	}
+	opts.Binds = append(opts.Binds,
+		Bind{Source: inputFile, Target: s.InputFile},
+		Bind{Source: outputFolder, Target: s.OutputFolder},
+	)
+	return nil
+}
+
+func touch(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
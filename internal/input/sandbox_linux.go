@@ -0,0 +1,37 @@
+//go:build linux
+
+package input
+
+import (
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// canMount probes, if gotap may bind mount in a new mount namespace. This
+// usually needs root, or CAP_SYS_ADMIN inside a container.
+func canMount() bool {
+	dir, err := os.MkdirTemp("", "gotap-probe-")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(dir)
+
+	result := make(chan bool)
+	go func() {
+		// the thread is never unlocked, so it is discarded together with
+		// the namespace once the goroutine returns
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNS); err != nil {
+			result <- false
+			return
+		}
+		if err := unix.Mount("none", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			result <- false
+			return
+		}
+		result <- unix.Mount(dir, dir, "", unix.MS_BIND, "") == nil
+	}()
+	return <-result
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package input
+
+// mount namespaces are linux only
+func canMount() bool {
+	return false
+}
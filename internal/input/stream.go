@@ -0,0 +1,101 @@
+package input
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// CappedBuffer keeps the last Limit bytes written to it in memory. A Limit
+// of zero or less keeps everything.
+type CappedBuffer struct {
+	Limit     int
+	Truncated bool
+	buf       bytes.Buffer
+	mu        sync.Mutex
+}
+
+func (b *CappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf.Write(p)
+	if b.Limit > 0 && b.buf.Len() > b.Limit {
+		b.buf.Next(b.buf.Len() - b.Limit)
+		b.Truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *CappedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// linePrefixWriter prepends every line with a timestamp and/or the name of
+// the stream, before passing it on to the wrapped writer.
+type linePrefixWriter struct {
+	w           io.Writer
+	stream      string
+	timestamps  bool
+	streamNames bool
+	lineStart   bool
+}
+
+func newLinePrefixWriter(w io.Writer, stream string, opts ExecutionOptions) io.Writer {
+	if !opts.Timestamps && !opts.StreamNames {
+		return w
+	}
+	return &linePrefixWriter{
+		w:           w,
+		stream:      stream,
+		timestamps:  opts.Timestamps,
+		streamNames: opts.StreamNames,
+		lineStart:   true,
+	}
+}
+
+func (l *linePrefixWriter) prefix() []byte {
+	var prefix []byte
+	if l.timestamps {
+		prefix = time.Now().UTC().AppendFormat(prefix, time.RFC3339Nano)
+		prefix = append(prefix, ' ')
+	}
+	if l.streamNames {
+		prefix = append(prefix, '[')
+		prefix = append(prefix, l.stream...)
+		prefix = append(prefix, "] "...)
+	}
+	return prefix
+}
+
+func (l *linePrefixWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	var out []byte
+	for len(p) > 0 {
+		if l.lineStart {
+			out = append(out, l.prefix()...)
+			l.lineStart = false
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			out = append(out, p...)
+			break
+		}
+		out = append(out, p[:i+1]...)
+		p = p[i+1:]
+		l.lineStart = true
+	}
+
+	_, err := l.w.Write(out)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,239 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ExecutableUsage summarizes the resources used by all processes of the
+// tool's process tree that share the same executable name.
+type ExecutableUsage struct {
+	Name          string `json:"name"`
+	Processes     int    `json:"processes"`
+	MemoryMax     uint64 `json:"memory_max_bytes"`
+	CPUMax        uint64 `json:"cpu_max_permille"`
+	ReadBytesSum  uint64 `json:"read_bytes_sum"`
+	WriteBytesSum uint64 `json:"write_bytes_sum"`
+}
+
+type processInfo struct {
+	name       string
+	ppid       int32
+	alive      bool
+	readBytes  uint64
+	writeBytes uint64
+	// lastRead and lastWrite are the counters of the previous reading
+	lastRead  uint64
+	lastWrite uint64
+}
+
+// processTree samples the tool process together with all its descendants,
+// as the actual work is usually done in children of the sh wrapper.
+type processTree struct {
+	root         *process.Process
+	seen         map[int32]*processInfo
+	processMax   int
+	fdMax        int32
+	trackFDs     bool
+	memoryMax    map[string]uint64
+	cpuMax       map[string]uint64
+	readBytes    uint64
+	writeBytes   uint64
+	procChildren bool
+}
+
+func newProcessTree(root *process.Process) *processTree {
+	return &processTree{
+		root:         root,
+		seen:         make(map[int32]*processInfo),
+		memoryMax:    make(map[string]uint64),
+		cpuMax:       make(map[string]uint64),
+		procChildren: true,
+	}
+}
+
+// childPids reads the children of all threads of pid from procfs.
+func childPids(pid int32) ([]int32, error) {
+	tasks, err := filepath.Glob(fmt.Sprintf("/proc/%d/task/*/children", pid))
+	if err != nil || len(tasks) == 0 {
+		return nil, fmt.Errorf("no children listed for process %d", pid)
+	}
+
+	var pids []int32
+	for _, task := range tasks {
+		content, err := os.ReadFile(task)
+		if err != nil {
+			// the thread or process exited in the meantime
+			continue
+		}
+		for _, field := range strings.Fields(string(content)) {
+			if child, err := strconv.ParseInt(field, 10, 32); err == nil {
+				pids = append(pids, int32(child))
+			}
+		}
+	}
+	return pids, nil
+}
+
+// descendants walks the tree using /proc/<pid>/task/*/children. If that is
+// not available, the parent ids of all processes are scanned instead.
+func (t *processTree) descendants() []*process.Process {
+	procs := []*process.Process{t.root}
+
+	if t.procChildren {
+		for i := 0; i < len(procs); i++ {
+			pids, err := childPids(procs[i].Pid)
+			if err != nil {
+				if i == 0 {
+					t.procChildren = false
+					return t.descendants()
+				}
+				continue
+			}
+			for _, pid := range pids {
+				if child, err := process.NewProcess(pid); err == nil {
+					procs = append(procs, child)
+				}
+			}
+		}
+		return procs
+	}
+
+	all, err := process.Processes()
+	if err != nil {
+		return procs
+	}
+	children := make(map[int32][]*process.Process)
+	for _, proc := range all {
+		ppid, err := proc.Ppid()
+		if err == nil {
+			children[ppid] = append(children[ppid], proc)
+		}
+	}
+	for i := 0; i < len(procs); i++ {
+		procs = append(procs, children[procs[i].Pid]...)
+	}
+	return procs
+}
+
+// countIO adds the increase of the io counters since the last reading.
+// When a child is reaped, the kernel adds its counters to the parent, so
+// the part already counted for the child is subtracted again.
+func (t *processTree) countIO(pid int32, info *processInfo, read uint64, write uint64) {
+	for _, child := range t.seen {
+		if child.ppid != pid || child.alive || child.lastRead == 0 && child.lastWrite == 0 {
+			continue
+		}
+		info.lastRead += child.lastRead
+		info.lastWrite += child.lastWrite
+		child.lastRead, child.lastWrite = 0, 0
+	}
+
+	if read > info.lastRead {
+		info.readBytes += read - info.lastRead
+		t.readBytes += read - info.lastRead
+	}
+	if write > info.lastWrite {
+		info.writeBytes += write - info.lastWrite
+		t.writeBytes += write - info.lastWrite
+	}
+	info.lastRead, info.lastWrite = read, write
+}
+
+func (t *processTree) sample(now time.Time) ResourceSample {
+	sample := ResourceSample{Timestamp: now}
+	memory := make(map[string]uint64)
+	cpu := make(map[string]uint64)
+
+	for _, info := range t.seen {
+		info.alive = false
+	}
+
+	procs := t.descendants()
+	for _, proc := range procs {
+		info, ok := t.seen[proc.Pid]
+		if !ok {
+			name, err := proc.Name()
+			if err != nil {
+				continue
+			}
+			ppid, _ := proc.Ppid()
+			info = &processInfo{name: name, ppid: ppid}
+			t.seen[proc.Pid] = info
+		}
+		info.alive = true
+	}
+
+	for _, proc := range procs {
+		info, ok := t.seen[proc.Pid]
+		if !ok {
+			continue
+		}
+		sample.Processes++
+
+		if mem, err := proc.MemoryInfo(); err == nil {
+			sample.RSS += mem.RSS
+			memory[info.name] += mem.RSS
+		}
+		if percent, err := proc.CPUPercent(); err == nil {
+			sample.CPU += uint64(percent * 1000)
+			cpu[info.name] += uint64(percent * 1000)
+		}
+		if ioCounters, err := proc.IOCounters(); err == nil {
+			t.countIO(proc.Pid, info, ioCounters.ReadBytes, ioCounters.WriteBytes)
+		}
+		if t.trackFDs {
+			if fds, err := proc.NumFDs(); err == nil {
+				t.fdMax = max(t.fdMax, fds)
+			}
+		}
+	}
+
+	// the totals keep the io of processes, which already exited
+	sample.ReadBytes = t.readBytes
+	sample.WriteBytes = t.writeBytes
+
+	t.processMax = max(t.processMax, sample.Processes)
+	for name, value := range memory {
+		t.memoryMax[name] = max(t.memoryMax[name], value)
+	}
+	for name, value := range cpu {
+		t.cpuMax[name] = max(t.cpuMax[name], value)
+	}
+
+	return sample
+}
+
+func (t *processTree) executables() []ExecutableUsage {
+	usage := make(map[string]*ExecutableUsage)
+	for _, info := range t.seen {
+		exe, ok := usage[info.name]
+		if !ok {
+			exe = &ExecutableUsage{
+				Name:      info.name,
+				MemoryMax: t.memoryMax[info.name],
+				CPUMax:    t.cpuMax[info.name],
+			}
+			usage[info.name] = exe
+		}
+		exe.Processes++
+		exe.ReadBytesSum += info.readBytes
+		exe.WriteBytesSum += info.writeBytes
+	}
+
+	executables := make([]ExecutableUsage, 0, len(usage))
+	for _, exe := range usage {
+		executables = append(executables, *exe)
+	}
+	sort.Slice(executables, func(i, j int) bool {
+		return executables[i].Name < executables[j].Name
+	})
+	return executables
+}
@@ -0,0 +1,32 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveDataPath finds a dataset referenced in inputs.json. Paths are
+// used as they are if they exist. Otherwise, relative paths and paths
+// inside the /in mount of the container are looked up next to inputs.json,
+// so that tools can be run outside of their container as well.
+func ResolveDataPath(path string, inputFile string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+
+	inputDir := filepath.Dir(inputFile)
+	var candidate string
+	if rel, ok := strings.CutPrefix(filepath.ToSlash(path), "/in/"); ok {
+		candidate = filepath.Join(inputDir, filepath.FromSlash(rel))
+	} else if !filepath.IsAbs(path) {
+		candidate = filepath.Join(inputDir, path)
+	}
+
+	if candidate != "" {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
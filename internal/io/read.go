@@ -6,6 +6,7 @@ import (
 
 	"github.com/alexander-lindner/go-cff"
 	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"gopkg.in/yaml.v3"
 )
 
 func ReadSpecFile(path string) (toolspec.SpecFile, error) {
@@ -58,3 +59,78 @@ func ReadLicenseFile(path string) (string, error) {
 
 	return string(licenseBuffer), nil
 }
+
+// ToolExtras holds the fields of a tool in tool.yml, which are used by
+// gotap but are not part of the tool-spec itself.
+type ToolExtras struct {
+	Version string                `yaml:"version,omitempty"`
+	Timeout string                `yaml:"timeout,omitempty"`
+	Limits  ToolLimits            `yaml:"limits,omitempty"`
+	Data    map[string]DataExtras `yaml:"data,omitempty"`
+	Outputs map[string]OutputSpec `yaml:"outputs,omitempty"`
+}
+
+// OutputSpec declares a file the tool is expected to write to the output
+// folder. Without a glob, the file is found by its name.
+type OutputSpec struct {
+	Description string     `yaml:"description,omitempty"`
+	Extension   StringList `yaml:"extension,omitempty"`
+	Optional    bool       `yaml:"optional,omitempty"`
+	Glob        string     `yaml:"glob,omitempty"`
+}
+
+// StringList is either a single string or a list of strings in YAML, like
+// the extension of datasets.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// DataExtras describe the content of a dataset. They are currently only
+// used for CSV files.
+type DataExtras struct {
+	Delimiter string       `yaml:"delimiter,omitempty"`
+	Columns   []ColumnSpec `yaml:"columns,omitempty"`
+}
+
+type ColumnSpec struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type,omitempty"`
+	Optional bool   `yaml:"optional,omitempty"`
+}
+
+// ToolLimits are the resource limits declared for a tool. Sizes and
+// durations are kept as strings, as they can also be set as flags.
+type ToolLimits struct {
+	Memory    string `yaml:"memory,omitempty"`
+	CPUTime   string `yaml:"cpu_time,omitempty"`
+	OpenFiles uint64 `yaml:"open_files,omitempty"`
+	Processes uint64 `yaml:"processes,omitempty"`
+}
+
+func ReadToolExtras(path string, toolname string) (ToolExtras, error) {
+	specBuffer, err := os.ReadFile(path)
+	if err != nil {
+		return ToolExtras{}, fmt.Errorf("failed to read tool spec file: %w", err)
+	}
+
+	var extras struct {
+		Tools map[string]ToolExtras `yaml:"tools"`
+	}
+	err = yaml.Unmarshal(specBuffer, &extras)
+	if err != nil {
+		return ToolExtras{}, fmt.Errorf("failed to load tool spec file: %w", err)
+	}
+
+	return extras.Tools[toolname], nil
+}
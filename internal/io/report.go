@@ -0,0 +1,220 @@
+package io
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/hydrocode-de/tool-spec-go/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationReport is the serializable outcome of a validation.
+type ValidationReport struct {
+	Tool         string                      `json:"tool" yaml:"tool"`
+	Status       string                      `json:"status" yaml:"status"`
+	SpecFile     string                      `json:"spec_file" yaml:"spec_file"`
+	InputFile    string                      `json:"input_file" yaml:"input_file"`
+	ErrorCount   int                         `json:"error_count" yaml:"error_count"`
+	WarningCount int                         `json:"warning_count" yaml:"warning_count"`
+	Errors       []*validate.ValidationError `json:"errors" yaml:"errors"`
+	Warnings     []*validate.ValidationError `json:"warnings" yaml:"warnings"`
+}
+
+var ReportFormats = []string{"text", "json", "yaml", "junit", "sarif"}
+
+func WriteValidationReport(report ValidationReport, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(report, "", "  ")
+	case "yaml":
+		return yaml.Marshal(report)
+	case "junit":
+		return writeJUnitReport(report)
+	case "sarif":
+		return writeSarifReport(report)
+	}
+
+	return nil, fmt.Errorf("unknown output format %s. Use one of %v", format, ReportFormats)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport reports every error as a failed test case. Warnings
+// are passed test cases, with the warning as output.
+func writeJUnitReport(report ValidationReport) ([]byte, error) {
+	suite := junitSuite{Name: report.Tool}
+
+	for _, warning := range report.Warnings {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s.%s", warning.Field, warning.Name),
+			ClassName: report.Tool,
+			SystemOut: WriteValidationError(warning, true),
+		})
+	}
+	for _, err := range report.Errors {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s.%s", err.Field, err.Name),
+			ClassName: report.Tool,
+			Failure: &junitFailure{
+				Type:    string(err.Type),
+				Message: err.Message,
+				Text:    fmt.Sprintf("Expected: %s\nActual: %s", err.Expected, err.Actual),
+			},
+		})
+	}
+	if len(suite.TestCases) == 0 {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      "tool-spec",
+			ClassName: report.Tool,
+		})
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Failures = report.ErrorCount
+
+	data, err := xml.MarshalIndent(junitTestSuites{
+		Name:     "gotap verify",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// writeSarifReport uses the error types as rules. Errors in parameters
+// and data are located in the inputs file, everything else in tool.yml.
+func writeSarifReport(report ValidationReport) ([]byte, error) {
+	driver := sarifDriver{
+		Name:           "gotap",
+		InformationURI: "https://vforwater.github.io/tool-specs",
+		Rules:          make([]sarifRule, 0),
+	}
+	results := make([]sarifResult, 0, report.ErrorCount+report.WarningCount)
+	knownRules := make(map[string]bool)
+
+	add := func(validationError *validate.ValidationError, level string) {
+		ruleID := string(validationError.Type)
+		if !knownRules[ruleID] {
+			knownRules[ruleID] = true
+			driver.Rules = append(driver.Rules, sarifRule{ID: ruleID})
+		}
+
+		uri := report.SpecFile
+		if validationError.Field == validate.Parameters || validationError.Field == validate.Data {
+			uri = report.InputFile
+		}
+
+		results = append(results, sarifResult{
+			RuleID:  ruleID,
+			Level:   level,
+			Message: sarifMessage{Text: validationError.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+				},
+			}},
+			Properties: map[string]string{
+				"tool":     report.Tool,
+				"field":    string(validationError.Field),
+				"name":     validationError.Name,
+				"expected": validationError.Expected,
+				"actual":   validationError.Actual,
+			},
+		})
+	}
+
+	for _, warning := range report.Warnings {
+		add(warning, "warning")
+	}
+	for _, err := range report.Errors {
+		add(err, "error")
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: driver},
+			Results: results,
+		}},
+	}, "", "  ")
+}
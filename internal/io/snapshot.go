@@ -0,0 +1,70 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// FolderSnapshot records the size and modification time of the files in a
+// folder, to tell the files written by a run from the ones, which were
+// already there.
+type FolderSnapshot map[string]fileState
+
+// SnapshotFolder lists the files below folder. A missing folder has no
+// files.
+func SnapshotFolder(folder string) (FolderSnapshot, error) {
+	snapshot := make(FolderSnapshot)
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == folder && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		snapshot[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", folder, err)
+	}
+	return snapshot, nil
+}
+
+// Changed lists the files below folder, which are new or changed since the
+// snapshot. The paths are relative to folder, use slashes and are sorted.
+func (s FolderSnapshot) Changed(folder string) ([]string, error) {
+	after, err := SnapshotFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, 0)
+	for path, state := range after {
+		if previous, ok := s[path]; ok && previous == state {
+			continue
+		}
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
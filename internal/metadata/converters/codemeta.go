@@ -0,0 +1,147 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexander-lindner/go-cff"
+	"github.com/hydrocode-de/gotap/internal/metadata"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+type CodeMeta struct {
+	Context             string          `json:"@context"`
+	Type                string          `json:"@type"`
+	Name                string          `json:"name,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	Version             string          `json:"version,omitempty"`
+	Author              []CodeMetaAgent `json:"author,omitempty"`
+	License             string          `json:"license,omitempty"`
+	CodeRepository      string          `json:"codeRepository,omitempty"`
+	URL                 string          `json:"url,omitempty"`
+	Identifier          string          `json:"identifier,omitempty"`
+	Keywords            []string        `json:"keywords,omitempty"`
+	DatePublished       string          `json:"datePublished,omitempty"`
+	ApplicationCategory string          `json:"applicationCategory,omitempty"`
+}
+
+type CodeMetaAgent struct {
+	Type        string                `json:"@type"`
+	ID          string                `json:"@id,omitempty"`
+	GivenName   string                `json:"givenName,omitempty"`
+	FamilyName  string                `json:"familyName,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	Email       string                `json:"email,omitempty"`
+	URL         string                `json:"url,omitempty"`
+	Affiliation *CodeMetaOrganization `json:"affiliation,omitempty"`
+}
+
+type CodeMetaOrganization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type CodeMetaConverter struct {
+	CodeMeta
+	errs []error
+}
+
+func (c *CodeMetaConverter) Ingest(spec toolspec.ToolSpec) {
+	citation := spec.Citation
+	if citation.Title == "" {
+		c.errs = append(c.errs, fmt.Errorf("critical. no citation found"))
+	}
+
+	c.CodeMeta = CodeMeta{
+		Context:             "https://w3id.org/codemeta/3.0",
+		Type:                "SoftwareSourceCode",
+		Name:                spec.Title,
+		Description:         spec.Description,
+		Version:             citation.Version,
+		Keywords:            citation.Keywords,
+		CodeRepository:      urlString(citation.RepositoryCode),
+		URL:                 urlString(citation.Url),
+		ApplicationCategory: "tool-spec",
+	}
+	if c.CodeMeta.CodeRepository == "" {
+		c.CodeMeta.CodeRepository = urlString(citation.Repository)
+	}
+	if citation.Doi.General != "" {
+		c.CodeMeta.Identifier = fmt.Sprintf("https://doi.org/%s.%s/%s", citation.Doi.General, citation.Doi.DirectoryIndicator, citation.Doi.RegistrantCode)
+	}
+	if !citation.DateReleased.Time().IsZero() {
+		c.CodeMeta.DatePublished = citation.DateReleased.Time().Format("2006-01-02")
+	}
+	if len(citation.License.Data) > 0 {
+		c.CodeMeta.License = spdxURL(string(citation.License.Data[0]))
+	}
+
+	for _, author := range citation.Authors {
+		if author.IsPerson {
+			agent := CodeMetaAgent{
+				Type:       "Person",
+				ID:         string(author.Person.Orcid),
+				GivenName:  author.Person.GivenNames,
+				FamilyName: author.Person.Family,
+				Email:      author.Person.Email,
+				URL:        urlString(author.Person.Website),
+			}
+			if author.Person.Affiliation != "" {
+				agent.Affiliation = &CodeMetaOrganization{
+					Type: "Organization",
+					Name: author.Person.Affiliation,
+				}
+			}
+			c.CodeMeta.Author = append(c.CodeMeta.Author, agent)
+		} else {
+			c.CodeMeta.Author = append(c.CodeMeta.Author, CodeMetaAgent{
+				Type:  "Organization",
+				ID:    string(author.Entity.Orcid),
+				Name:  author.Entity.Name,
+				Email: author.Entity.Email,
+				URL:   urlString(author.Entity.Website),
+			})
+		}
+	}
+}
+
+// IngestLicense uses the LICENSE file, if CITATION.cff does not name a license.
+func (c *CodeMetaConverter) IngestLicense(license string) {
+	if c.CodeMeta.License != "" {
+		return
+	}
+	if id := metadata.DetectSPDX(license); id != "" {
+		c.CodeMeta.License = spdxURL(id)
+	}
+}
+
+func (c *CodeMetaConverter) Validate() error {
+	return errors.Join(c.errs...)
+}
+
+func (c *CodeMetaConverter) Serialize(format string) ([]byte, error) {
+	return metadata.Serialize(c.CodeMeta, codeMetaVocabulary, format)
+}
+
+// CodeMeta 3.0 maps all terms used here to schema.org
+var codeMetaVocabulary = metadata.Vocabulary{
+	Prefix:   "schema",
+	Base:     "http://schema.org/",
+	IRITerms: []string{"url", "license", "codeRepository", "identifier"},
+}
+
+func spdxURL(id string) string {
+	return "https://spdx.org/licenses/" + id
+}
+
+// urlString is safe to use with unset cff urls
+func urlString(u cff.URL) string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.URL.String()
+}
+
+func init() {
+	metadata.Register(func() metadata.Converter { return &CodeMetaConverter{} }, "json", "codemeta")
+}
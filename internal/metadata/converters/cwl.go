@@ -0,0 +1,224 @@
+package converters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hydrocode-de/gotap/internal/metadata"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"gopkg.in/yaml.v3"
+)
+
+// CWL is a CWL v1.2 CommandLineTool, which runs the tool through gotap
+// inside of its docker image.
+type CWL struct {
+	CWLVersion   string               `json:"cwlVersion" yaml:"cwlVersion"`
+	Class        string               `json:"class" yaml:"class"`
+	ID           string               `json:"id,omitempty" yaml:"id,omitempty"`
+	Label        string               `json:"label,omitempty" yaml:"label,omitempty"`
+	Doc          string               `json:"doc,omitempty" yaml:"doc,omitempty"`
+	Namespaces   map[string]string    `json:"$namespaces,omitempty" yaml:"$namespaces,omitempty"`
+	BaseCommand  []string             `json:"baseCommand" yaml:"baseCommand"`
+	Requirements CWLRequirements      `json:"requirements" yaml:"requirements"`
+	Inputs       map[string]CWLInput  `json:"inputs" yaml:"inputs"`
+	Outputs      map[string]CWLOutput `json:"outputs" yaml:"outputs"`
+}
+
+type CWLRequirements struct {
+	DockerRequirement           CWLDocker         `json:"DockerRequirement" yaml:"DockerRequirement"`
+	InlineJavascriptRequirement struct{}          `json:"InlineJavascriptRequirement" yaml:"InlineJavascriptRequirement"`
+	InitialWorkDirRequirement   CWLInitialWorkDir `json:"InitialWorkDirRequirement" yaml:"InitialWorkDirRequirement"`
+	EnvVarRequirement           CWLEnvVar         `json:"EnvVarRequirement" yaml:"EnvVarRequirement"`
+}
+
+type CWLDocker struct {
+	DockerPull string `json:"dockerPull" yaml:"dockerPull"`
+}
+
+type CWLInitialWorkDir struct {
+	Listing []CWLDirent `json:"listing" yaml:"listing"`
+}
+
+type CWLDirent struct {
+	Entryname string `json:"entryname" yaml:"entryname"`
+	Entry     string `json:"entry" yaml:"entry"`
+}
+
+type CWLEnvVar struct {
+	EnvDef map[string]string `json:"envDef" yaml:"envDef"`
+}
+
+type CWLInput struct {
+	Type    interface{} `json:"type" yaml:"type"`
+	Doc     string      `json:"doc,omitempty" yaml:"doc,omitempty"`
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Format  []string    `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+type CWLOutput struct {
+	Type          string           `json:"type" yaml:"type"`
+	Doc           string           `json:"doc,omitempty" yaml:"doc,omitempty"`
+	OutputBinding CWLOutputBinding `json:"outputBinding" yaml:"outputBinding"`
+}
+
+type CWLOutputBinding struct {
+	Glob string `json:"glob" yaml:"glob"`
+}
+
+// cwlTypes maps tool-spec parameter types to CWL types. Dates and times are
+// passed on as ISO 8601 strings.
+var cwlTypes = map[string]string{
+	"string":   "string",
+	"integer":  "long",
+	"float":    "double",
+	"boolean":  "boolean",
+	"datetime": "string",
+	"date":     "string",
+	"time":     "string",
+	"enum":     "string",
+}
+
+// edamFormats are used as format hints for the known file extensions
+var edamFormats = map[string]string{
+	"csv":  "edam:format_3752",
+	"tsv":  "edam:format_3475",
+	"json": "edam:format_3464",
+	"txt":  "edam:format_2330",
+	"nc":   "edam:format_3650",
+	"tif":  "edam:format_3591",
+	"tiff": "edam:format_3591",
+	"xml":  "edam:format_2332",
+	"yml":  "edam:format_3750",
+	"yaml": "edam:format_3750",
+}
+
+const cwlInputFile = "/in/inputs.json"
+
+type CWLConverter struct {
+	CWL
+	errs []error
+}
+
+func (c *CWLConverter) Ingest(spec toolspec.ToolSpec) {
+	c.CWL = CWL{
+		CWLVersion:  "v1.2",
+		Class:       "CommandLineTool",
+		ID:          spec.Name,
+		Label:       spec.Title,
+		Doc:         spec.Description,
+		BaseCommand: []string{"gotap", "run", spec.Name},
+		Requirements: CWLRequirements{
+			InitialWorkDirRequirement: CWLInitialWorkDir{
+				Listing: []CWLDirent{{Entryname: cwlInputFile, Entry: cwlInputsExpression(spec)}},
+			},
+			EnvVarRequirement: CWLEnvVar{EnvDef: map[string]string{
+				"TAP_INPUT_FILE":    cwlInputFile,
+				"TAP_OUTPUT_FOLDER": "$(runtime.outdir)",
+				"TAP_SPEC_FILE":     "/src/tool.yml",
+				"TAP_CITATION_FILE": "/src/CITATION.cff",
+				"TAP_LICENSE_FILE":  "/src/LICENSE",
+			}},
+		},
+		Inputs: make(map[string]CWLInput),
+		Outputs: map[string]CWLOutput{
+			"results": {
+				Type:          "File[]",
+				Doc:           "All files written to the output folder by the tool",
+				OutputBinding: CWLOutputBinding{Glob: "*"},
+			},
+		},
+	}
+
+	for name, param := range spec.Parameters {
+		var typ interface{} = cwlTypes[param.ToolType]
+		if param.ToolType == "enum" {
+			typ = map[string]interface{}{"type": "enum", "symbols": param.Values}
+		}
+		if typ == "" {
+			c.errs = append(c.errs, fmt.Errorf("parameter %s has the unknown type %s", name, param.ToolType))
+			typ = "string"
+		}
+		if param.IsArray {
+			typ = map[string]interface{}{"type": "array", "items": typ}
+		}
+		if param.Optional || param.Default != nil {
+			typ = []interface{}{"null", typ}
+		}
+		c.CWL.Inputs[name] = CWLInput{Type: typ, Doc: param.Description, Default: param.Default}
+	}
+
+	for name, data := range spec.Data {
+		input := CWLInput{Type: "File", Doc: data.Description}
+		for _, ext := range data.Extensions {
+			if format, ok := edamFormats[strings.ToLower(strings.TrimPrefix(ext, "."))]; ok {
+				input.Format = append(input.Format, format)
+			}
+		}
+		if len(input.Format) > 0 {
+			c.CWL.Namespaces = map[string]string{"edam": "http://edamontology.org/"}
+		}
+		c.CWL.Inputs[name] = input
+	}
+}
+
+// IngestImage sets the docker image, which has gotap and the tool installed.
+// There is no sensible default, as the image name is not part of tool.yml.
+func (c *CWLConverter) IngestImage(image string) {
+	c.CWL.Requirements.DockerRequirement.DockerPull = image
+}
+
+// cwlInputsExpression builds the inputs.json of the tool from the CWL
+// inputs. Datasets are passed by the path CWL staged them to.
+func cwlInputsExpression(spec toolspec.ToolSpec) string {
+	var b strings.Builder
+	b.WriteString("${\n  var parameters = {};\n  var data = {};\n")
+	for _, name := range sortedKeys(spec.Parameters) {
+		key, _ := json.Marshal(name)
+		fmt.Fprintf(&b, "  if (inputs[%s] !== null) { parameters[%s] = inputs[%s]; }\n", key, key, key)
+	}
+	for _, name := range sortedKeys(spec.Data) {
+		key, _ := json.Marshal(name)
+		fmt.Fprintf(&b, "  if (inputs[%s] !== null) { data[%s] = inputs[%s].path; }\n", key, key, key)
+	}
+	tool, _ := json.Marshal(spec.Name)
+	fmt.Fprintf(&b, "  return JSON.stringify({%s: {\"parameters\": parameters, \"data\": data}});\n}\n", tool)
+	return b.String()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *CWLConverter) Validate() error {
+	errs := c.errs
+	if c.CWL.Requirements.DockerRequirement.DockerPull == "" {
+		errs = append(errs, fmt.Errorf("the docker image of the tool is needed to run it. Pass it as --docker-image"))
+	}
+	return errors.Join(errs...)
+}
+
+// Serialize writes the CWL document as YAML, which is what most CWL runners
+// expect, or as JSON.
+func (c *CWLConverter) Serialize(format string) ([]byte, error) {
+	switch format {
+	case "", "yaml":
+		return yaml.Marshal(c.CWL)
+	case "pretty":
+		return json.MarshalIndent(c.CWL, "", "  ")
+	case "compact":
+		return json.Marshal(c.CWL)
+	}
+	return nil, fmt.Errorf("the cwl format can only be serialized as yaml, pretty or compact")
+}
+
+func init() {
+	metadata.Register(func() metadata.Converter { return &CWLConverter{} }, "cwl", "cwl")
+}
@@ -0,0 +1,208 @@
+package converters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/metadata"
+	"github.com/hydrocode-de/gotap/internal/schema"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// OGCProcess is a process description of OGC API - Processes - Part 1: Core
+type OGCProcess struct {
+	ID                 string               `json:"id"`
+	Title              string               `json:"title,omitempty"`
+	Description        string               `json:"description,omitempty"`
+	Version            string               `json:"version"`
+	Keywords           []string             `json:"keywords,omitempty"`
+	JobControlOptions  []string             `json:"jobControlOptions"`
+	OutputTransmission []string             `json:"outputTransmission"`
+	Inputs             map[string]OGCInput  `json:"inputs"`
+	Outputs            map[string]OGCOutput `json:"outputs"`
+}
+
+type OGCInput struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	MinOccurs   int            `json:"minOccurs"`
+	MaxOccurs   interface{}    `json:"maxOccurs"`
+	Schema      *schema.Schema `json:"schema"`
+}
+
+type OGCOutput struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      *schema.Schema `json:"schema"`
+}
+
+// mediaTypes complements the mime package for common data formats, which
+// are usually not known to the system
+var mediaTypes = map[string]string{
+	"csv":  "text/csv",
+	"tsv":  "text/tab-separated-values",
+	"nc":   "application/x-netcdf",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"json": "application/json",
+	"yml":  "application/yaml",
+	"yaml": "application/yaml",
+	"txt":  "text/plain",
+}
+
+func mediaType(extension string) string {
+	extension = strings.ToLower(strings.TrimPrefix(extension, "."))
+	if mediaType, ok := mediaTypes[extension]; ok {
+		return mediaType
+	}
+	if mediaType := mime.TypeByExtension("." + extension); mediaType != "" {
+		return strings.Split(mediaType, ";")[0]
+	}
+	return "application/octet-stream"
+}
+
+// fileSchema describes a binary file with one of the extensions.
+func fileSchema(extensions []string) *schema.Schema {
+	switch len(extensions) {
+	case 0:
+		return &schema.Schema{Type: "string", ContentEncoding: "binary"}
+	case 1:
+		return &schema.Schema{Type: "string", ContentEncoding: "binary", ContentMediaType: mediaType(extensions[0])}
+	}
+
+	fileSchema := &schema.Schema{}
+	for _, ext := range extensions {
+		fileSchema.OneOf = append(fileSchema.OneOf, &schema.Schema{
+			Type:             "string",
+			ContentEncoding:  "binary",
+			ContentMediaType: mediaType(ext),
+		})
+	}
+	return fileSchema
+}
+
+type OGCProcessConverter struct {
+	OGCProcess
+	errs []error
+}
+
+func (o *OGCProcessConverter) Ingest(spec toolspec.ToolSpec) {
+	o.OGCProcess = OGCProcess{
+		ID:                 spec.Name,
+		Title:              spec.Title,
+		Description:        spec.Description,
+		Version:            spec.Citation.Version,
+		Keywords:           spec.Citation.Keywords,
+		JobControlOptions:  []string{"sync-execute", "async-execute"},
+		OutputTransmission: []string{"value", "reference"},
+		Inputs:             make(map[string]OGCInput),
+		Outputs: map[string]OGCOutput{
+			"stdout": {
+				Title:  "STDOUT",
+				Schema: &schema.Schema{Type: "string", ContentMediaType: "text/plain"},
+			},
+			"stderr": {
+				Title:  "STDERR",
+				Schema: &schema.Schema{Type: "string", ContentMediaType: "text/plain"},
+			},
+			"metadata": {
+				Title:       "Run metadata",
+				Description: "Exit code and resource usage of the run",
+				Schema:      &schema.Schema{Type: "object", ContentMediaType: "application/json"},
+			},
+		},
+	}
+
+	for name, param := range spec.Parameters {
+		paramSchema, err := schema.Parameter(param)
+		if err != nil {
+			o.errs = append(o.errs, err)
+			continue
+		}
+
+		input := OGCInput{
+			Title:       name,
+			Description: param.Description,
+			MinOccurs:   1,
+			MaxOccurs:   1,
+			Schema:      paramSchema,
+		}
+		if param.Optional || param.Default != nil {
+			input.MinOccurs = 0
+		}
+		// arrays are described as repeated inputs, like OGC clients expect.
+		// The items do not take the default of the whole array, so arrays
+		// with a default keep the array schema instead.
+		if param.IsArray && param.Default == nil {
+			input.MaxOccurs = "unbounded"
+			input.Schema = paramSchema.Items
+		}
+		// the description is already part of the input. The schema is
+		// copied, as the items are shared with the array schema
+		inputSchema := *input.Schema
+		inputSchema.Description = ""
+		input.Schema = &inputSchema
+		o.OGCProcess.Inputs[name] = input
+	}
+
+	for name, data := range spec.Data {
+		input := OGCInput{
+			Title:       name,
+			Description: data.Description,
+			MinOccurs:   1,
+			MaxOccurs:   1,
+			Schema:      fileSchema(data.Extensions),
+		}
+		o.OGCProcess.Inputs[name] = input
+	}
+}
+
+// IngestOutputs lists the files declared as outputs in tool.yml next to
+// the outputs every run has.
+func (o *OGCProcessConverter) IngestOutputs(outputs map[string]io.OutputSpec) {
+	for name, output := range outputs {
+		if _, ok := o.OGCProcess.Outputs[name]; ok {
+			o.errs = append(o.errs, fmt.Errorf("the output %s is reserved for the runs of gotap", name))
+			continue
+		}
+		o.OGCProcess.Outputs[name] = OGCOutput{
+			Title:       name,
+			Description: output.Description,
+			Schema:      fileSchema(output.Extension),
+		}
+	}
+}
+
+// IngestVersion prefers the version from tool.yml over the one from
+// CITATION.cff.
+func (o *OGCProcessConverter) IngestVersion(version string) {
+	if version != "" {
+		o.OGCProcess.Version = version
+	}
+}
+
+func (o *OGCProcessConverter) Validate() error {
+	errs := o.errs
+	if o.OGCProcess.Version == "" {
+		errs = append(errs, fmt.Errorf("a process needs a version. Set it in tool.yml or CITATION.cff"))
+	}
+	return errors.Join(errs...)
+}
+
+func (o *OGCProcessConverter) Serialize(format string) ([]byte, error) {
+	switch format {
+	case "", "pretty":
+		return json.MarshalIndent(o.OGCProcess, "", "  ")
+	case "compact":
+		return json.Marshal(o.OGCProcess)
+	}
+	return nil, fmt.Errorf("the ogcapi-process format can only be serialized as pretty or compact")
+}
+
+func init() {
+	metadata.Register(func() metadata.Converter { return &OGCProcessConverter{} }, "json", "ogcapi-process", "ogcapi")
+}
@@ -1,9 +1,10 @@
 package converters
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/hydrocode-de/gotap/internal/metadata"
 	toolspec "github.com/hydrocode-de/tool-spec-go"
 )
 
@@ -71,7 +72,7 @@ func (s *SchemaOrgConverter) Ingest(spec toolspec.ToolSpec) {
 					Type:   "Person",
 					Name:   author.Person.Family + ", " + author.Person.GivenNames,
 					Email:  author.Person.Email,
-					URL:    author.Person.Website.URL.String(),
+					URL:    urlString(author.Person.Website),
 					SameAs: string(author.Person.Orcid),
 				}
 				if len(author.Person.Affiliation) > 0 {
@@ -81,7 +82,7 @@ func (s *SchemaOrgConverter) Ingest(spec toolspec.ToolSpec) {
 				person = Person{
 					Type: "Organization",
 					Name: author.Entity.Name,
-					URL:  author.Entity.Website.URL.String(),
+					URL:  urlString(author.Entity.Website),
 				}
 			}
 			s.SchemaOrg.Author = append(s.SchemaOrg.Author, person)
@@ -102,10 +103,20 @@ func (s *SchemaOrgConverter) Ingest(spec toolspec.ToolSpec) {
 	}
 }
 
-func (s *SchemaOrgConverter) Validate() bool {
-	return s.errs == nil
+func (s *SchemaOrgConverter) Validate() error {
+	return errors.Join(s.errs...)
 }
 
 func (s *SchemaOrgConverter) Serialize(format string) ([]byte, error) {
-	return json.MarshalIndent(s.SchemaOrg, "", "  ")
+	return metadata.Serialize(s.SchemaOrg, schemaOrgVocabulary, format)
+}
+
+var schemaOrgVocabulary = metadata.Vocabulary{
+	Prefix:   "schema",
+	Base:     "http://schema.org/",
+	IRITerms: []string{"url", "sameAs", "license", "codeRepository"},
+}
+
+func init() {
+	metadata.Register(func() metadata.Converter { return &SchemaOrgConverter{} }, "jsonld", "schema.org", "schemaorg")
 }
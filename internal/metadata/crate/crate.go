@@ -0,0 +1,384 @@
+package crate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/metadata/converters"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+const (
+	FileName               = "ro-crate-metadata.json"
+	processProfile         = "https://w3id.org/ro/wfrun/process/0.5"
+	formalParameterProfile = "https://bioschemas.org/profiles/FormalParameter/1.0-RELEASE"
+)
+
+// Run describes a finished tool execution, which is packaged as RO-Crate.
+type Run struct {
+	Spec         toolspec.ToolSpec
+	Input        toolspec.ToolInput
+	InputFile    string
+	OutputFolder string
+	StartTime    time.Time
+	EndTime      time.Time
+	ExitCode     int
+	// Pending files are written to the output folder after the crate, like
+	// the MANIFEST, and are listed as results nevertheless.
+	Pending []string
+}
+
+type entity map[string]interface{}
+
+func ref(id string) entity {
+	return entity{"@id": id}
+}
+
+// BuildRunCrate describes the run as RO-Crate 1.1, following the Process
+// Run Crate profile of the Workflow Run Crate family. The tool is the
+// instrument of a CreateAction, which uses the datasets and parameters
+// from inputs.json as objects and has every file of the output folder as
+// result. The inputs declared in tool.yml are described as FormalParameter
+// of the tool, which the objects are an exampleOfWork of.
+func BuildRunCrate(run Run) ([]byte, error) {
+	runID := "#run-" + randomID()
+	toolID := "#tool-" + run.Spec.Name
+
+	tool, err := toolEntity(run.Spec, toolID)
+	if err != nil {
+		return nil, err
+	}
+	inputs, parameterEntities := formalParameters(run.Spec, toolID)
+	tool["input"] = inputs
+
+	objects, objectEntities := inputEntities(run, toolID)
+	results, resultEntities, err := outputEntities(run.OutputFolder, run.Pending)
+	if err != nil {
+		return nil, err
+	}
+
+	status := "http://schema.org/CompletedActionStatus"
+	if run.ExitCode != 0 {
+		status = "http://schema.org/FailedActionStatus"
+	}
+	action := entity{
+		"@id":          runID,
+		"@type":        "CreateAction",
+		"name":         fmt.Sprintf("Run of %s", run.Spec.Name),
+		"instrument":   ref(toolID),
+		"object":       objects,
+		"result":       results,
+		"startTime":    run.StartTime.Format(time.RFC3339),
+		"endTime":      run.EndTime.Format(time.RFC3339),
+		"actionStatus": status,
+		"exitCode":     run.ExitCode,
+	}
+	if run.ExitCode != 0 {
+		action["error"] = fmt.Sprintf("the tool exited with code %d", run.ExitCode)
+	}
+
+	graph := []entity{
+		{
+			"@id":        FileName,
+			"@type":      "CreativeWork",
+			"conformsTo": ref("https://w3id.org/ro/crate/1.1"),
+			"about":      ref("./"),
+		},
+		{
+			"@id":           "./",
+			"@type":         "Dataset",
+			"name":          fmt.Sprintf("Results of %s", run.Spec.Title),
+			"description":   fmt.Sprintf("Output folder of a run of the tool %s", run.Spec.Name),
+			"datePublished": run.EndTime.Format(time.RFC3339),
+			"conformsTo":    []entity{ref(processProfile)},
+			"hasPart":       results,
+			"mentions":      ref(runID),
+		},
+		{
+			"@id":     processProfile,
+			"@type":   "CreativeWork",
+			"name":    "Process Run Crate",
+			"version": "0.5",
+		},
+		{
+			"@id":     formalParameterProfile,
+			"@type":   "CreativeWork",
+			"name":    "FormalParameter profile",
+			"version": "1.0-RELEASE",
+		},
+		tool,
+		action,
+	}
+	graph = append(graph, parameterEntities...)
+	graph = append(graph, objectEntities...)
+	graph = append(graph, resultEntities...)
+
+	return json.MarshalIndent(map[string]interface{}{
+		"@context": []interface{}{
+			"https://w3id.org/ro/crate/1.1/context",
+			map[string]string{"exitCode": "https://w3id.org/ro/terms/workflow-run#exitCode"},
+		},
+		"@graph": graph,
+	}, "", "  ")
+}
+
+// toolEntity reuses the schema.org description of the tool.
+func toolEntity(spec toolspec.ToolSpec, id string) (entity, error) {
+	converter := &converters.SchemaOrgConverter{}
+	converter.Ingest(spec)
+
+	data, err := json.Marshal(converter.SchemaOrg)
+	if err != nil {
+		return nil, err
+	}
+	var tool entity
+	if err := json.Unmarshal(data, &tool); err != nil {
+		return nil, err
+	}
+
+	delete(tool, "@context")
+	tool["@id"] = id
+	return tool, nil
+}
+
+// parameterTypes maps the parameter types of tool.yml to the types of
+// schema.org used as additionalType of a FormalParameter.
+var parameterTypes = map[string]string{
+	"string":   "Text",
+	"enum":     "Text",
+	"integer":  "Integer",
+	"float":    "Float",
+	"boolean":  "Boolean",
+	"date":     "Date",
+	"time":     "Time",
+	"datetime": "DateTime",
+	"file":     "File",
+}
+
+func parameterID(toolID string, name string) string {
+	return toolID + "-param-" + name
+}
+
+func dataID(toolID string, name string) string {
+	return toolID + "-data-" + name
+}
+
+// formalParameters describes the parameters and datasets declared in
+// tool.yml as inputs of the tool.
+func formalParameters(spec toolspec.ToolSpec, toolID string) ([]entity, []entity) {
+	refs := make([]entity, 0)
+	entities := make([]entity, 0)
+
+	dataNames := make([]string, 0, len(spec.Data))
+	for name := range spec.Data {
+		dataNames = append(dataNames, name)
+	}
+	sort.Strings(dataNames)
+	for _, name := range dataNames {
+		id := dataID(toolID, name)
+		parameter := entity{
+			"@id":            id,
+			"@type":          "FormalParameter",
+			"conformsTo":     ref(formalParameterProfile),
+			"name":           name,
+			"additionalType": "File",
+			"valueRequired":  true,
+		}
+		if description := spec.Data[name].Description; description != "" {
+			parameter["description"] = description
+		}
+		formats := make([]string, 0)
+		for _, extension := range spec.Data[name].Extensions {
+			if format := mime.TypeByExtension("." + extension); format != "" {
+				formats = append(formats, format)
+			}
+		}
+		if len(formats) > 0 {
+			parameter["encodingFormat"] = formats
+		}
+		refs = append(refs, ref(id))
+		entities = append(entities, parameter)
+	}
+
+	paramNames := make([]string, 0, len(spec.Parameters))
+	for name := range spec.Parameters {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		param := spec.Parameters[name]
+		id := parameterID(toolID, name)
+		additionalType, ok := parameterTypes[param.ToolType]
+		if !ok {
+			additionalType = "Text"
+		}
+		parameter := entity{
+			"@id":            id,
+			"@type":          "FormalParameter",
+			"conformsTo":     ref(formalParameterProfile),
+			"name":           name,
+			"additionalType": additionalType,
+			"valueRequired":  !param.Optional && param.Default == nil,
+		}
+		if param.Description != "" {
+			parameter["description"] = param.Description
+		}
+		if param.IsArray {
+			parameter["multipleValues"] = true
+		}
+		if param.Default != nil {
+			defaultValue := param.Default
+			if _, ok := defaultValue.(string); !ok {
+				encoded, _ := json.Marshal(defaultValue)
+				defaultValue = string(encoded)
+			}
+			parameter["defaultValue"] = defaultValue
+		}
+		refs = append(refs, ref(id))
+		entities = append(entities, parameter)
+	}
+
+	return refs, entities
+}
+
+func inputEntities(run Run, toolID string) ([]entity, []entity) {
+	refs := make([]entity, 0)
+	entities := make([]entity, 0)
+
+	dataNames := make([]string, 0, len(run.Input.Datasets))
+	for name := range run.Input.Datasets {
+		dataNames = append(dataNames, name)
+	}
+	sort.Strings(dataNames)
+	for _, name := range dataNames {
+		path := run.Input.Datasets[name]
+		resolved := io.ResolveDataPath(path, run.InputFile)
+		if absolute, err := filepath.Abs(resolved); err == nil {
+			resolved = absolute
+		}
+		// the inputs are not part of the crate, so they are referenced by
+		// their location on the machine the tool ran on
+		id := (&url.URL{Scheme: "file", Path: filepath.ToSlash(resolved)}).String()
+		file := entity{
+			"@id":           id,
+			"@type":         "File",
+			"name":          name,
+			"alternateName": path,
+		}
+		if _, ok := run.Spec.Data[name]; ok {
+			file["exampleOfWork"] = ref(dataID(toolID, name))
+		}
+		if info, err := os.Stat(resolved); err == nil {
+			file["contentSize"] = fmt.Sprint(info.Size())
+		}
+		if format := mime.TypeByExtension(filepath.Ext(path)); format != "" {
+			file["encodingFormat"] = format
+		}
+		refs = append(refs, ref(id))
+		entities = append(entities, file)
+	}
+
+	paramNames := make([]string, 0, len(run.Input.Parameters))
+	for name := range run.Input.Parameters {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		id := "#param-" + name
+		value := run.Input.Parameters[name]
+		if _, ok := value.(string); !ok {
+			encoded, _ := json.Marshal(value)
+			value = string(encoded)
+		}
+		parameter := entity{
+			"@id":   id,
+			"@type": "PropertyValue",
+			"name":  name,
+			"value": value,
+		}
+		if _, ok := run.Spec.Parameters[name]; ok {
+			parameter["exampleOfWork"] = ref(parameterID(toolID, name))
+		}
+		refs = append(refs, ref(id))
+		entities = append(entities, parameter)
+	}
+
+	return refs, entities
+}
+
+func outputEntities(outputFolder string, pending []string) ([]entity, []entity, error) {
+	refs := make([]entity, 0)
+	entities := make([]entity, 0)
+
+	err := filepath.WalkDir(outputFolder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(outputFolder, path)
+		// pending files are listed below, even if an old version of them
+		// is still in the output folder
+		if err != nil || rel == FileName || slices.Contains(pending, rel) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		id := fileID(rel)
+		file := entity{
+			"@id":          id,
+			"@type":        "File",
+			"name":         d.Name(),
+			"contentSize":  fmt.Sprint(info.Size()),
+			"dateModified": info.ModTime().Format(time.RFC3339),
+		}
+		if format := mime.TypeByExtension(filepath.Ext(path)); format != "" {
+			file["encodingFormat"] = format
+		}
+		refs = append(refs, ref(id))
+		entities = append(entities, file)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the output folder: %w", err)
+	}
+
+	for _, name := range pending {
+		id := fileID(name)
+		refs = append(refs, ref(id))
+		entities = append(entities, entity{
+			"@id":   id,
+			"@type": "File",
+			"name":  filepath.Base(name),
+		})
+	}
+
+	return refs, entities, nil
+}
+
+// fileID percent-encodes the path of a file in the crate, as the @id is a
+// URI reference relative to the crate.
+func fileID(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).String()
+}
+
+func randomID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"regexp"
+	"strings"
+)
+
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+-]+)`)
+
+// licenseTitles identify licenses by their title. The title has to start a
+// line and is matched against that line and the next non-empty one, joined
+// by a newline, as most titles put the version into a line of its own.
+// Matching the title only keeps the licenses, which mention others in
+// their body, from being mistaken for them.
+var licenseTitles = []struct {
+	id    string
+	title *regexp.Regexp
+	// gnu licenses are -only or -or-later depending on the notice
+	gnu bool
+}{
+	{"GPL-3.0", regexp.MustCompile(`^gnu general public license\nversion 3\b`), true},
+	{"GPL-2.0", regexp.MustCompile(`^gnu general public license\nversion 2\b`), true},
+	{"AGPL-3.0", regexp.MustCompile(`^gnu affero general public license\nversion 3\b`), true},
+	{"LGPL-3.0", regexp.MustCompile(`^gnu lesser general public license\nversion 3\b`), true},
+	{"LGPL-2.1", regexp.MustCompile(`^gnu lesser general public license\nversion 2\.1\b`), true},
+	{"LGPL-2.0", regexp.MustCompile(`^gnu library general public license\nversion 2\b`), true},
+	{"Apache-2.0", regexp.MustCompile(`^apache license,?\s+version 2\.0\b`), false},
+	{"MPL-2.0", regexp.MustCompile(`^mozilla public license,?\s+version 2\.0\b`), false},
+	{"MPL-1.1", regexp.MustCompile(`^mozilla public license,?\s+version 1\.1\b`), false},
+	{"EUPL-1.2", regexp.MustCompile(`^european union public licen[cs]e\s+v\. ?1\.2\b`), false},
+	{"CC-BY-4.0", regexp.MustCompile(`^(creative commons )?attribution 4\.0 international( public license)?\n`), false},
+	{"CC0-1.0", regexp.MustCompile(`^cc0 1\.0 universal\n`), false},
+	{"Unlicense", regexp.MustCompile(`^this is free and unencumbered software released into the public domain`), false},
+	{"BSD-3-Clause", regexp.MustCompile(`^bsd 3-clause license\n`), false},
+	{"BSD-2-Clause", regexp.MustCompile(`^bsd 2-clause license\n`), false},
+	{"MIT", regexp.MustCompile(`^mit license\n`), false},
+}
+
+// licenseMarkers identify the permissive licenses, which usually come
+// without a title, by phrases of their text. All phrases of an entry have
+// to be present; the first match wins.
+var licenseMarkers = []struct {
+	id      string
+	phrases []string
+}{
+	{"BSD-4-Clause", []string{"redistribution and use in source and binary forms", "all advertising materials mentioning"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+}
+
+// DetectSPDX guesses the SPDX id of a license text. An explicit
+// SPDX-License-Identifier takes precedence. The GNU licenses are only
+// identified, if a notice of the project in front of the license text
+// tells whether later versions apply, as the license text itself does not.
+// If the license is not known, an empty string is returned.
+func DetectSPDX(license string) string {
+	if match := spdxIdentifier.FindStringSubmatch(license); match != nil {
+		return match[1]
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(license, "\n") {
+		if line = strings.ToLower(strings.Join(strings.Fields(line), " ")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	for i, line := range lines {
+		if i+1 < len(lines) {
+			line += "\n" + lines[i+1]
+		} else {
+			line += "\n"
+		}
+		for _, title := range licenseTitles {
+			if !title.title.MatchString(line) {
+				continue
+			}
+			if title.gnu {
+				return gnuVersion(title.id, strings.Join(lines[:i], " "))
+			}
+			return title.id
+		}
+	}
+
+	text := strings.Join(lines, " ")
+	for _, marker := range licenseMarkers {
+		found := true
+		for _, phrase := range marker.phrases {
+			if !strings.Contains(text, phrase) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return marker.id
+		}
+	}
+
+	return ""
+}
+
+// gnuVersion appends -only or -or-later to the id of a GNU license, as
+// stated by the notice of the project. Without a notice, the version can
+// not be told and an empty string is returned.
+func gnuVersion(id string, notice string) string {
+	if !strings.Contains(notice, "general public license") && !strings.Contains(notice, "gpl") {
+		return ""
+	}
+	if strings.Contains(notice, "any later version") || strings.Contains(notice, "or later") {
+		return id + "-or-later"
+	}
+	return id + "-only"
+}
@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	orLaterNotice = `Copyright (C) 2024 The Authors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+`
+	onlyNotice = `Copyright (C) 2024 The Authors
+
+This library is licensed under the GNU Lesser General Public License,
+version 2.1 only.
+
+`
+)
+
+func TestDetectSPDX(t *testing.T) {
+	tests := []struct {
+		file   string
+		notice string
+		want   string
+	}{
+		{file: "Apache-2.0", want: "Apache-2.0"},
+		{file: "MPL-2.0", want: "MPL-2.0"},
+		{file: "MPL-1.1", want: "MPL-1.1"},
+		{file: "CC0-1.0", want: "CC0-1.0"},
+		{file: "BSD", want: "BSD-3-Clause"},
+		{file: "MIT", want: "MIT"},
+		// without a notice, the GNU licenses can not tell -only from -or-later
+		{file: "GPL-3", want: ""},
+		{file: "GPL-2", want: ""},
+		{file: "LGPL-3", want: ""},
+		{file: "LGPL-2.1", want: ""},
+		{file: "LGPL-2", want: ""},
+		{file: "GPL-3", notice: orLaterNotice, want: "GPL-3.0-or-later"},
+		{file: "GPL-2", notice: orLaterNotice, want: "GPL-2.0-or-later"},
+		{file: "LGPL-3", notice: orLaterNotice, want: "LGPL-3.0-or-later"},
+		{file: "LGPL-2.1", notice: onlyNotice, want: "LGPL-2.1-only"},
+		{file: "LGPL-2", notice: onlyNotice, want: "LGPL-2.0-only"},
+		{file: "GPL-3", notice: "SPDX-License-Identifier: GPL-3.0-only\n\n", want: "GPL-3.0-only"},
+		{file: "GFDL-1.3", want: ""},
+		{file: "Artistic", want: ""},
+	}
+
+	for _, test := range tests {
+		license, err := os.ReadFile(filepath.Join("testdata", "licenses", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := DetectSPDX(test.notice + string(license)); got != test.want {
+			t.Errorf("DetectSPDX(%s, notice %t) = %q, want %q", test.file, test.notice != "", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package prov
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/checksum"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+const FileName = "provenance.json"
+
+// Run describes a finished tool execution, which is recorded as PROV-JSON.
+// Datasets are the checksums of the datasets, see checksum.HashDatasets.
+// Generated lists the files written by the tool, relative to the output
+// folder.
+type Run struct {
+	Spec         toolspec.ToolSpec
+	ToolVersion  string
+	Image        string
+	ImageDigest  string
+	Input        toolspec.ToolInput
+	Datasets     checksum.Datasets
+	OutputFolder string
+	Generated    []string
+	StartTime    time.Time
+	EndTime      time.Time
+	ExitCode     int
+	GotapVersion string
+}
+
+type record map[string]interface{}
+
+func typed(value interface{}, typ string) record {
+	return record{"$": value, "type": typ}
+}
+
+// BuildRunProvenance describes the run as W3C PROV-JSON document. The run
+// is an activity, which used the datasets and parameters, generated the
+// files written by the tool and was associated with the tool and gotap.
+func BuildRunProvenance(run Run) ([]byte, error) {
+	runID := "run:" + randomID()
+	toolID := "tool:" + run.Spec.Name
+	gotapID := "tool:gotap"
+
+	entities := make(map[string]record)
+	used := make(map[string]record)
+	generated := make(map[string]record)
+
+	dataNames := make([]string, 0, len(run.Input.Datasets))
+	for name := range run.Input.Datasets {
+		dataNames = append(dataNames, name)
+	}
+	sort.Strings(dataNames)
+	for _, name := range dataNames {
+		path := run.Input.Datasets[name]
+		id := "input:" + name
+		entity := record{
+			"prov:label":    name,
+			"prov:type":     typed("gotap:Dataset", "prov:QUALIFIED_NAME"),
+			"prov:location": path,
+		}
+		// the sum of a directory is the one of its manifest, not of a file
+		if sum, ok := run.Datasets.Sums[name]; ok && run.Datasets.Dirs[name] {
+			entity["gotap:manifestSha256"] = sum
+		} else if ok {
+			entity["gotap:sha256"] = sum
+		}
+		entities[id] = entity
+		used["_:used-"+name] = record{"prov:activity": runID, "prov:entity": id, "prov:role": "gotap:data"}
+	}
+
+	paramNames := make([]string, 0, len(run.Input.Parameters))
+	for name := range run.Input.Parameters {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		id := "param:" + name
+		value := run.Input.Parameters[name]
+		if _, ok := value.(string); !ok {
+			encoded, _ := json.Marshal(value)
+			value = string(encoded)
+		}
+		entities[id] = record{
+			"prov:label": name,
+			"prov:type":  typed("gotap:Parameter", "prov:QUALIFIED_NAME"),
+			"prov:value": value,
+		}
+		used["_:used-param-"+name] = record{"prov:activity": runID, "prov:entity": id, "prov:role": "gotap:parameter"}
+	}
+
+	for _, rel := range run.Generated {
+		sum, err := checksum.File(filepath.Join(run.OutputFolder, filepath.FromSlash(rel)), checksum.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash the output %s: %w", rel, err)
+		}
+
+		id := "output:" + rel
+		entities[id] = record{
+			"prov:label":    rel,
+			"prov:location": rel,
+			"gotap:sha256":  sum,
+		}
+		generated["_:gen-"+rel] = record{
+			"prov:activity": runID,
+			"prov:entity":   id,
+			"prov:time":     run.EndTime.Format(time.RFC3339),
+		}
+	}
+
+	tool := record{
+		"prov:type":  typed("prov:SoftwareAgent", "prov:QUALIFIED_NAME"),
+		"prov:label": run.Spec.Title,
+		"gotap:name": run.Spec.Name,
+	}
+	if run.ToolVersion != "" {
+		tool["gotap:version"] = run.ToolVersion
+	}
+	if run.Image != "" {
+		tool["gotap:image"] = run.Image
+	}
+	if run.ImageDigest != "" {
+		tool["gotap:imageDigest"] = run.ImageDigest
+	}
+
+	document := record{
+		"prefix": record{
+			"gotap":  "https://github.com/hydrocode-de/gotap#",
+			"tool":   "urn:gotap:tool:",
+			"run":    "urn:gotap:run:",
+			"input":  "urn:gotap:input:",
+			"param":  "urn:gotap:param:",
+			"output": "urn:gotap:output:",
+		},
+		"agent": record{
+			toolID: tool,
+			gotapID: record{
+				"prov:type":     typed("prov:SoftwareAgent", "prov:QUALIFIED_NAME"),
+				"prov:label":    "gotap",
+				"gotap:version": run.GotapVersion,
+			},
+		},
+		"activity": record{
+			runID: record{
+				"prov:label":     fmt.Sprintf("Run of %s", run.Spec.Name),
+				"prov:startTime": run.StartTime.Format(time.RFC3339Nano),
+				"prov:endTime":   run.EndTime.Format(time.RFC3339Nano),
+				"gotap:exitCode": typed(run.ExitCode, "xsd:int"),
+			},
+		},
+		"entity": entities,
+		"used":   used,
+		"wasAssociatedWith": record{
+			"_:assoc-tool":  record{"prov:activity": runID, "prov:agent": toolID, "prov:role": "gotap:tool"},
+			"_:assoc-gotap": record{"prov:activity": runID, "prov:agent": gotapID, "prov:role": "gotap:runner"},
+		},
+	}
+	if len(generated) > 0 {
+		document["wasGeneratedBy"] = generated
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func randomID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
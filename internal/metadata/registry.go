@@ -0,0 +1,69 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format is a registered metadata format. The first name is the primary
+// one, which is also used as file name.
+type Format struct {
+	Names     []string
+	Extension string
+	New       func() Converter
+}
+
+func (f Format) FileName() string {
+	return f.Names[0] + "." + f.Extension
+}
+
+var formats = make(map[string]*Format)
+
+// Register makes a converter available under one or more names. It is
+// meant to be called from the init function of the converter.
+func Register(factory func() Converter, extension string, names ...string) {
+	if len(names) == 0 {
+		panic("metadata: a converter needs at least one name")
+	}
+
+	format := &Format{Names: names, Extension: extension, New: factory}
+	for _, name := range names {
+		if _, ok := formats[name]; ok {
+			panic(fmt.Sprintf("metadata: format %s is registered twice", name))
+		}
+		formats[name] = format
+	}
+}
+
+func Lookup(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown format %s. Use one of: %s", name, strings.Join(FormatNames(), ", "))
+	}
+	return *format, nil
+}
+
+// Formats lists all registered formats, sorted by their primary name.
+func Formats() []Format {
+	seen := make(map[*Format]bool)
+	list := make([]Format, 0, len(formats))
+	for _, format := range formats {
+		if !seen[format] {
+			seen[format] = true
+			list = append(list, *format)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Names[0] < list[j].Names[0]
+	})
+	return list
+}
+
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for _, format := range Formats() {
+		names = append(names, format.Names[0])
+	}
+	return names
+}
@@ -0,0 +1,357 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	rdfType = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	xsd     = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// Serializations lists the values accepted by Converter.Serialize. An
+// empty format is the same as pretty.
+var Serializations = []string{"pretty", "compact", "expanded", "yaml", "turtle", "ntriples"}
+
+// serializationExtensions are the file extensions of serializations, which
+// are not JSON.
+var serializationExtensions = map[string]string{
+	"yaml":     "yaml",
+	"turtle":   "ttl",
+	"ntriples": "nt",
+}
+
+// Vocabulary maps the terms of a compacted JSON-LD document to IRIs. This
+// replaces the remote @context, which is not resolved by gotap.
+type Vocabulary struct {
+	Prefix string
+	Base   string
+	// Terms lists terms, which are not mapped to Base + term
+	Terms map[string]string
+	// IRITerms lists terms, whose values are IRIs instead of literals
+	IRITerms []string
+}
+
+func (v Vocabulary) iri(term string) string {
+	if iri, ok := v.Terms[term]; ok {
+		return iri
+	}
+	if strings.Contains(term, ":") {
+		return term
+	}
+	return v.Base + term
+}
+
+func (v Vocabulary) isIRITerm(term string) bool {
+	for _, t := range v.IRITerms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize writes a compacted JSON-LD document in one of the
+// Serializations. The document is expanded using the vocabulary for the
+// expanded and RDF serializations.
+func Serialize(doc interface{}, vocab Vocabulary, format string) ([]byte, error) {
+	switch format {
+	case "", "pretty":
+		return json.MarshalIndent(doc, "", "  ")
+	case "compact":
+		return json.Marshal(doc)
+	}
+
+	// all other serializations work on the generic representation
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "yaml":
+		return yaml.Marshal(generic)
+	case "expanded":
+		return json.MarshalIndent([]interface{}{expandNode(generic, vocab)}, "", "  ")
+	case "ntriples":
+		triples := toTriples(expandNode(generic, vocab))
+		return writeNTriples(triples), nil
+	case "turtle":
+		triples := toTriples(expandNode(generic, vocab))
+		return writeTurtle(triples, vocab), nil
+	}
+
+	return nil, fmt.Errorf("unknown serialization %s. Use one of %v", format, Serializations)
+}
+
+// SerializedFileName returns the file name of the format in the given
+// serialization.
+func (f Format) SerializedFileName(serialization string) string {
+	if extension, ok := serializationExtensions[serialization]; ok {
+		return f.Names[0] + "." + extension
+	}
+	return f.FileName()
+}
+
+func expandNode(node map[string]interface{}, vocab Vocabulary) map[string]interface{} {
+	expanded := make(map[string]interface{})
+
+	for key, value := range node {
+		switch key {
+		case "@context":
+			continue
+		case "@id":
+			expanded["@id"] = value
+			continue
+		case "@type":
+			types := make([]interface{}, 0)
+			for _, t := range asList(value) {
+				types = append(types, vocab.iri(fmt.Sprint(t)))
+			}
+			expanded["@type"] = types
+			continue
+		}
+
+		values := make([]interface{}, 0)
+		for _, v := range asList(value) {
+			switch typed := v.(type) {
+			case map[string]interface{}:
+				values = append(values, expandNode(typed, vocab))
+			case string:
+				if vocab.isIRITerm(key) {
+					values = append(values, map[string]interface{}{"@id": typed})
+				} else {
+					values = append(values, map[string]interface{}{"@value": typed})
+				}
+			case nil:
+				continue
+			default:
+				values = append(values, map[string]interface{}{"@value": typed})
+			}
+		}
+		if len(values) > 0 {
+			expanded[vocab.iri(key)] = values
+		}
+	}
+
+	return expanded
+}
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// term is an IRI, blank node or literal in a triple
+type term struct {
+	iri      string
+	blank    string
+	literal  string
+	datatype string
+}
+
+type triple struct {
+	subject   term
+	predicate string
+	object    term
+}
+
+func toTriples(node map[string]interface{}) []triple {
+	triples := make([]triple, 0)
+	blankCount := 0
+
+	var walk func(node map[string]interface{}) term
+	walk = func(node map[string]interface{}) term {
+		var subject term
+		if id, ok := node["@id"].(string); ok && strings.Contains(id, ":") {
+			subject = term{iri: id}
+		} else {
+			subject = term{blank: fmt.Sprintf("b%d", blankCount)}
+			blankCount++
+		}
+
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if key == "@id" {
+				continue
+			}
+			if key == "@type" {
+				for _, t := range node[key].([]interface{}) {
+					triples = append(triples, triple{subject, rdfType, term{iri: t.(string)}})
+				}
+				continue
+			}
+			for _, value := range node[key].([]interface{}) {
+				object := value.(map[string]interface{})
+				if literal, ok := object["@value"]; ok {
+					triples = append(triples, triple{subject, key, literalTerm(literal)})
+				} else if id, ok := object["@id"].(string); ok && len(object) == 1 {
+					triples = append(triples, triple{subject, key, term{iri: id}})
+				} else {
+					triples = append(triples, triple{subject, key, walk(object)})
+				}
+			}
+		}
+		return subject
+	}
+
+	walk(node)
+	return triples
+}
+
+func literalTerm(value interface{}) term {
+	switch typed := value.(type) {
+	case bool:
+		return term{literal: strconv.FormatBool(typed), datatype: xsd + "boolean"}
+	case float64:
+		if typed == float64(int64(typed)) {
+			return term{literal: strconv.FormatInt(int64(typed), 10), datatype: xsd + "integer"}
+		}
+		return term{literal: strconv.FormatFloat(typed, 'E', -1, 64), datatype: xsd + "double"}
+	}
+	return term{literal: fmt.Sprint(value)}
+}
+
+func escapeLiteral(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// iriRef writes an IRI as IRIREF of N-Triples and Turtle. The characters,
+// which are not allowed in there, are escaped as UCHAR.
+func iriRef(iri string) string {
+	var b strings.Builder
+	b.WriteString("<")
+	for _, r := range iri {
+		if r <= 0x20 || strings.ContainsRune(`<>"{}|^`+"`"+`\`, r) {
+			fmt.Fprintf(&b, `\u%04X`, r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func (t term) nTriples() string {
+	switch {
+	case t.iri != "":
+		return iriRef(t.iri)
+	case t.blank != "":
+		return "_:" + t.blank
+	case t.datatype != "":
+		return `"` + escapeLiteral(t.literal) + `"^^` + iriRef(t.datatype)
+	}
+	return `"` + escapeLiteral(t.literal) + `"`
+}
+
+func writeNTriples(triples []triple) []byte {
+	var b strings.Builder
+	for _, t := range triples {
+		fmt.Fprintf(&b, "%s %s %s .\n", t.subject.nTriples(), iriRef(t.predicate), t.object.nTriples())
+	}
+	return []byte(b.String())
+}
+
+// turtleName shortens IRIs of the vocabulary to prefixed names.
+func turtleName(iri string, vocab Vocabulary) string {
+	if iri == rdfType {
+		return "a"
+	}
+	if local, ok := strings.CutPrefix(iri, vocab.Base); ok && vocab.Prefix != "" && isLocalName(local) {
+		return vocab.Prefix + ":" + local
+	}
+	if local, ok := strings.CutPrefix(iri, xsd); ok {
+		return "xsd:" + local
+	}
+	return iriRef(iri)
+}
+
+func isLocalName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) turtle(vocab Vocabulary) string {
+	switch {
+	case t.iri != "":
+		return turtleName(t.iri, vocab)
+	case t.blank != "":
+		return "_:" + t.blank
+	case t.datatype != "":
+		return `"` + escapeLiteral(t.literal) + `"^^` + turtleName(t.datatype, vocab)
+	}
+	return `"` + escapeLiteral(t.literal) + `"`
+}
+
+func writeTurtle(triples []triple, vocab Vocabulary) []byte {
+	var b strings.Builder
+	if vocab.Prefix != "" {
+		fmt.Fprintf(&b, "@prefix %s: %s .\n", vocab.Prefix, iriRef(vocab.Base))
+	}
+	fmt.Fprintf(&b, "@prefix xsd: %s .\n", iriRef(xsd))
+
+	// triples are grouped by subject, in the order the subjects appear
+	var subjects []term
+	bySubject := make(map[term][]triple)
+	for _, t := range triples {
+		if _, ok := bySubject[t.subject]; !ok {
+			subjects = append(subjects, t.subject)
+		}
+		bySubject[t.subject] = append(bySubject[t.subject], t)
+	}
+
+	for _, subject := range subjects {
+		b.WriteString("\n" + subject.turtle(vocab))
+		for i, t := range bySubject[subject] {
+			separator := " ;"
+			if i == len(bySubject[subject])-1 {
+				separator = " ."
+			}
+			fmt.Fprintf(&b, "\n    %s %s%s", turtleName(t.predicate, vocab), t.object.turtle(vocab), separator)
+		}
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String())
+}
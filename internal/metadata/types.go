@@ -1,9 +1,38 @@
 package metadata
 
-import toolspec "github.com/hydrocode-de/tool-spec-go"
+import (
+	"github.com/hydrocode-de/gotap/internal/io"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
 
 type Converter interface {
 	Ingest(spec toolspec.ToolSpec)
-	Validate() bool
+	// Validate returns the problems found while ingesting the tool, which
+	// would result in an invalid document.
+	Validate() error
 	Serialize(format string) ([]byte, error)
 }
+
+// LicenseIngester is implemented by converters, which also make use of the
+// content of the tool's LICENSE file.
+type LicenseIngester interface {
+	IngestLicense(license string)
+}
+
+// ImageIngester is implemented by converters, which describe how to run the
+// tool from its docker image.
+type ImageIngester interface {
+	IngestImage(image string)
+}
+
+// VersionIngester is implemented by converters, which need the version of
+// the tool from tool.yml.
+type VersionIngester interface {
+	IngestVersion(version string)
+}
+
+// OutputsIngester is implemented by converters, which describe the outputs
+// the tool declares in tool.yml.
+type OutputsIngester interface {
+	IngestOutputs(outputs map[string]io.OutputSpec)
+}
@@ -0,0 +1,307 @@
+package scaffold
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Language describes how an entrypoint in a language is run inside the
+// tool container. The keys of Languages are the extensions of run.<lang>
+// as resolved by input.ResolveCommand.
+type Language struct {
+	BaseImage string
+	Setup     []string
+}
+
+var Languages = map[string]Language{
+	"sh": {BaseImage: "debian:bookworm-slim"},
+	"py": {BaseImage: "python:3.12-slim"},
+	"R": {
+		BaseImage: "r-base:4.4.1",
+		Setup:     []string{`R -e "install.packages('jsonlite', repos='https://cloud.r-project.org')"`},
+	},
+	"jl": {
+		BaseImage: "julia:1.10",
+		Setup:     []string{`julia -e 'using Pkg; Pkg.add("JSON")'`},
+	},
+	"pl": {BaseImage: "perl:5.40-slim"},
+	"m":  {BaseImage: "gnuoctave/octave:9.2.0"},
+	"js": {BaseImage: "node:22-slim"},
+}
+
+var ParameterTypes = []string{"string", "integer", "float", "boolean", "enum", "date", "datetime", "time"}
+
+type Parameter struct {
+	Name  string
+	Type  string
+	Array bool
+}
+
+type Dataset struct {
+	Name      string
+	Extension string
+}
+
+type Author struct {
+	GivenNames  string
+	FamilyNames string
+	Email       string
+}
+
+// Tool holds everything needed to scaffold a new tool.
+type Tool struct {
+	Name         string
+	Title        string
+	Description  string
+	Version      string
+	Lang         string
+	License      string
+	GotapRelease string
+	Authors      []Author
+	Parameters   []Parameter
+	Data         []Dataset
+}
+
+type scaffoldFile struct {
+	path     string
+	template string
+	mode     os.FileMode
+}
+
+// templateData is passed to all templates
+type templateData struct {
+	Tool
+	Language  Language
+	Year      int
+	Copyright string
+}
+
+// ParseParameter parses parameters given as name:type, where the type may
+// end with [] for arrays.
+func ParseParameter(value string) (Parameter, error) {
+	name, paramType, ok := strings.Cut(value, ":")
+	if !ok {
+		paramType = "string"
+	}
+	array := strings.HasSuffix(paramType, "[]")
+	paramType = strings.TrimSuffix(paramType, "[]")
+
+	if name == "" || !isParameterType(paramType) {
+		return Parameter{}, fmt.Errorf("invalid parameter %s. Use name:type with type one of %v", value, ParameterTypes)
+	}
+	return Parameter{Name: name, Type: paramType, Array: array}, nil
+}
+
+func isParameterType(paramType string) bool {
+	for _, t := range ParameterTypes {
+		if t == paramType {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDataset parses datasets given as name:extension.
+func ParseDataset(value string) (Dataset, error) {
+	name, extension, _ := strings.Cut(value, ":")
+	if name == "" || extension == "" {
+		return Dataset{}, fmt.Errorf("invalid data %s. Use name:extension", value)
+	}
+	return Dataset{Name: name, Extension: strings.TrimPrefix(extension, ".")}, nil
+}
+
+// ParseAuthor splits an author given as "Given Names Family <email>".
+func ParseAuthor(value string) Author {
+	var author Author
+	if start := strings.Index(value, "<"); start >= 0 {
+		author.Email = strings.Trim(value[start:], "<> ")
+		value = value[:start]
+	}
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, " "); i >= 0 {
+		author.GivenNames = value[:i]
+		author.FamilyNames = value[i+1:]
+	} else {
+		author.FamilyNames = value
+	}
+	return author
+}
+
+// Create writes the layout of a new tool into dir:
+//
+//	dir/Dockerfile
+//	dir/src/tool.yml, run.<lang>, CITATION.cff, LICENSE
+//	dir/in/inputs.json, <data>.<extension>
+//
+// Existing files are only replaced if force is set. The placeholder data
+// files are never replaced, as they might already hold the real data. The
+// created files are returned.
+func Create(dir string, tool Tool, force bool) ([]string, error) {
+	language, ok := Languages[tool.Lang]
+	if !ok {
+		return nil, fmt.Errorf("unknown language %s", tool.Lang)
+	}
+
+	data := templateData{
+		Tool:      tool,
+		Language:  language,
+		Year:      time.Now().Year(),
+		Copyright: "the authors",
+	}
+	if len(tool.Authors) > 0 {
+		names := make([]string, 0, len(tool.Authors))
+		for _, author := range tool.Authors {
+			names = append(names, strings.TrimSpace(author.GivenNames+" "+author.FamilyNames))
+		}
+		data.Copyright = strings.Join(names, ", ")
+	}
+
+	licenseTemplate := "LICENSE.tmpl"
+	if _, err := templates.Open("templates/LICENSE-" + tool.License + ".tmpl"); err == nil {
+		licenseTemplate = "LICENSE-" + tool.License + ".tmpl"
+	}
+
+	files := []scaffoldFile{
+		{filepath.Join(dir, "src", "tool.yml"), "tool.yml.tmpl", 0644},
+		{filepath.Join(dir, "src", "run."+tool.Lang), "run." + tool.Lang + ".tmpl", 0755},
+		{filepath.Join(dir, "src", "CITATION.cff"), "CITATION.cff.tmpl", 0644},
+		{filepath.Join(dir, "src", "LICENSE"), licenseTemplate, 0644},
+		{filepath.Join(dir, "Dockerfile"), "Dockerfile.tmpl", 0644},
+	}
+
+	inputsPath := filepath.Join(dir, "in", "inputs.json")
+	if !force {
+		paths := []string{inputsPath}
+		for _, file := range files {
+			paths = append(paths, file.path)
+		}
+		for _, path := range paths {
+			if _, err := os.Stat(path); err == nil {
+				return nil, fmt.Errorf("%s already exists. Use --force to overwrite it", path)
+			}
+		}
+	}
+
+	tmpl, err := template.New("scaffold").Funcs(template.FuncMap{
+		"quote": strconv.Quote,
+	}).ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	created := make([]string, 0, len(files)+1)
+	for _, file := range files {
+		var content strings.Builder
+		err := tmpl.ExecuteTemplate(&content, file.template, data)
+		if err != nil {
+			return created, fmt.Errorf("failed to render %s: %w", file.path, err)
+		}
+		if err := writeFile(file.path, content.String(), file.mode); err != nil {
+			return created, err
+		}
+		created = append(created, file.path)
+	}
+
+	inputs, err := io.InputFileToJSON(ExampleInputs(tool))
+	if err != nil {
+		return created, err
+	}
+	if err := writeFile(inputsPath, inputs+"\n", 0644); err != nil {
+		return created, err
+	}
+	created = append(created, inputsPath)
+
+	for _, dataset := range tool.Data {
+		path := filepath.Join(dir, "in", dataFileName(dataset))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := writeFile(path, placeholderData(dataset.Extension), 0644); err != nil {
+			return created, err
+		}
+		created = append(created, path)
+	}
+
+	return created, nil
+}
+
+func dataFileName(dataset Dataset) string {
+	return dataset.Name + "." + dataset.Extension
+}
+
+// placeholderData is the content of the placeholder data files. They are
+// not empty and tables get a header, so that the example inputs pass the
+// validation of gotap.
+func placeholderData(extension string) string {
+	switch strings.ToLower(extension) {
+	case "csv":
+		return "id,value\n"
+	case "tsv":
+		return "id\tvalue\n"
+	case "json":
+		return "{}\n"
+	}
+	return "placeholder, replace it with the real data\n"
+}
+
+func writeFile(path string, content string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ExampleInputs creates an inputs.json with a placeholder value for every
+// declared parameter and dataset. The datasets point to the placeholder
+// files next to inputs.json.
+func ExampleInputs(tool Tool) toolspec.InputFile {
+	parameters := make(map[string]interface{})
+	for _, param := range tool.Parameters {
+		var value interface{}
+		switch param.Type {
+		case "integer":
+			value = 0
+		case "float":
+			value = 0.0
+		case "boolean":
+			value = false
+		case "enum":
+			value = "option_a"
+		case "date", "datetime", "time":
+			value = "2025-01-01T00:00:00Z"
+		default:
+			value = ""
+		}
+		if param.Array {
+			value = []interface{}{value}
+		}
+		parameters[param.Name] = value
+	}
+
+	datasets := make(map[string]string)
+	for _, data := range tool.Data {
+		datasets[data.Name] = dataFileName(data)
+	}
+
+	return toolspec.InputFile{
+		tool.Name: toolspec.ToolInput{
+			Parameters: parameters,
+			Datasets:   datasets,
+		},
+	}
+}
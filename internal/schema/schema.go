@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// Schema is the subset of JSON Schema needed to describe tool inputs.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// types maps tool-spec parameter types to JSON Schema types and formats.
+// Dates and times are parsed as RFC 3339 timestamps by tool-spec, so all of
+// them use the date-time format.
+var types = map[string][2]string{
+	"string":   {"string", ""},
+	"integer":  {"integer", ""},
+	"float":    {"number", ""},
+	"boolean":  {"boolean", ""},
+	"enum":     {"string", ""},
+	"datetime": {"string", "date-time"},
+	"date":     {"string", "date-time"},
+	"time":     {"string", "date-time"},
+}
+
+// Parameter derives the schema of a single parameter value. Arrays are
+// described by the schema of their items.
+func Parameter(param toolspec.ParameterSpec) (*Schema, error) {
+	typ, ok := types[param.ToolType]
+	if !ok {
+		return nil, fmt.Errorf("parameter %s has the unknown type %s", param.Name, param.ToolType)
+	}
+
+	value := &Schema{
+		Type:    typ[0],
+		Format:  typ[1],
+		Minimum: param.Min,
+		Maximum: param.Max,
+	}
+	if param.ToolType == "enum" {
+		for _, v := range param.Values {
+			value.Enum = append(value.Enum, v)
+		}
+	}
+
+	if param.IsArray {
+		value = &Schema{Type: "array", Items: value}
+	}
+	value.Description = param.Description
+	value.Default = param.Default
+
+	return value, nil
+}
+
+// Draft is the JSON Schema dialect of the schemas created by Inputs.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Inputs derives the schema of an inputs.json file for one tool. It follows
+// validate.ValidateInputs as far as JSON Schema allows: unknown parameters
+// are rejected, optional parameters and parameters with a default may be
+// missing, only optional parameters, which are not arrays, may be null and
+// every dataset is required. The data files themselves are not checked.
+func Inputs(spec toolspec.ToolSpec) (*Schema, error) {
+	closed := false
+	parameters := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		Required:             make([]string, 0),
+		AdditionalProperties: &closed,
+	}
+	for name, param := range spec.Parameters {
+		param.Name = name
+		paramSchema, err := Parameter(param)
+		if err != nil {
+			return nil, err
+		}
+		if param.Optional && !param.IsArray {
+			paramSchema.Type = []interface{}{paramSchema.Type, "null"}
+			if paramSchema.Enum != nil {
+				paramSchema.Enum = append(paramSchema.Enum, nil)
+			}
+		}
+		if !param.Optional && param.Default == nil {
+			parameters.Required = append(parameters.Required, name)
+		}
+		parameters.Properties[name] = paramSchema
+	}
+	sort.Strings(parameters.Required)
+
+	data := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+		Required:   make([]string, 0),
+	}
+	for name, dataSpec := range spec.Data {
+		data.Properties[name] = &Schema{
+			Type:        "string",
+			Description: dataSpec.Description,
+			Pattern:     extensionPattern(dataSpec.Extensions),
+		}
+		data.Required = append(data.Required, name)
+	}
+	sort.Strings(data.Required)
+
+	tool := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"parameters": parameters,
+			"data":       data,
+		},
+	}
+	if len(parameters.Required) > 0 {
+		tool.Required = append(tool.Required, "parameters")
+	}
+	if len(data.Required) > 0 {
+		tool.Required = append(tool.Required, "data")
+	}
+
+	return &Schema{
+		Schema:      Draft,
+		Title:       spec.Title,
+		Description: spec.Description,
+		Type:        "object",
+		Properties:  map[string]*Schema{spec.Name: tool},
+		Required:    []string{spec.Name},
+	}, nil
+}
+
+// extensionPattern matches paths ending with one of the extensions. The
+// case-insensitive flag is not part of JSON Schema, so both cases are listed.
+func extensionPattern(extensions []string) string {
+	if len(extensions) == 0 {
+		return ""
+	}
+
+	alternatives := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		var b strings.Builder
+		for _, r := range strings.TrimPrefix(ext, ".") {
+			lower, upper := strings.ToLower(string(r)), strings.ToUpper(string(r))
+			if lower != upper {
+				b.WriteString("[" + lower + upper + "]")
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		alternatives = append(alternatives, b.String())
+	}
+	return `\.(` + strings.Join(alternatives, "|") + `)$`
+}
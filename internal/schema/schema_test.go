@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"slices"
+	"testing"
+
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+// accepts checks a JSON value against the subset of JSON Schema, which is
+// used by Inputs.
+func accepts(s *Schema, value interface{}) bool {
+	if s.Type != nil && !hasType(s.Type, value) {
+		return false
+	}
+	if s.Enum != nil && !slices.Contains(s.Enum, value) {
+		return false
+	}
+
+	switch typed := value.(type) {
+	case float64:
+		if s.Minimum != nil && typed < *s.Minimum || s.Maximum != nil && typed > *s.Maximum {
+			return false
+		}
+	case string:
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(typed) {
+			return false
+		}
+	case []interface{}:
+		for _, item := range typed {
+			if s.Items != nil && !accepts(s.Items, item) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := typed[name]; !ok {
+				return false
+			}
+		}
+		for name, property := range typed {
+			propertySchema, ok := s.Properties[name]
+			if !ok && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return false
+			}
+			if ok && !accepts(propertySchema, property) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasType(schemaType interface{}, value interface{}) bool {
+	types, ok := schemaType.([]interface{})
+	if !ok {
+		types = []interface{}{schemaType}
+	}
+	for _, t := range types {
+		switch typed := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && typed == math.Trunc(typed) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func float(value float64) *float64 {
+	return &value
+}
+
+// The date, time and datetime parameters are left out, as ValidateInputs
+// expects them to be decoded to time.Time, which inputs.json can not do.
+var testSpec = toolspec.ToolSpec{
+	Name: "tool",
+	Parameters: map[string]toolspec.ParameterSpec{
+		"text":     {ToolType: "string"},
+		"count":    {ToolType: "integer", Min: float(1), Max: float(10)},
+		"factor":   {ToolType: "float", Default: 1.5},
+		"flag":     {ToolType: "boolean", Optional: true},
+		"method":   {ToolType: "enum", Values: []string{"fast", "exact"}, Optional: true},
+		"values":   {ToolType: "integer", IsArray: true, Optional: true},
+		"defaults": {ToolType: "string", IsArray: true, Default: []interface{}{"a"}},
+	},
+	Data: map[string]toolspec.DataSpec{
+		"table": {Extensions: []string{"csv"}},
+	},
+}
+
+func TestInputsAgreesWithValidateInputs(t *testing.T) {
+	inputSchema, err := Inputs(testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		document string
+		accepted bool
+	}{
+		{`{"parameters": {"text": "a", "count": 3}, "data": {"table": "in/table.csv"}}`, true},
+		{`{"parameters": {"text": "a", "count": 3.0}, "data": {"table": "table.CSV"}}`, true},
+		{`{"parameters": {"text": "", "count": 10, "factor": 2, "flag": null, "method": null}, "data": {"table": "t.csv"}}`, true},
+		{`{"parameters": {"text": "a", "count": 1, "method": "exact", "values": [1, 2], "defaults": []}, "data": {"table": "t.csv"}}`, true},
+		{`{"parameters": {"text": "a", "count": 1, "flag": true, "values": []}, "data": {"table": "t.csv", "extra": "x.txt"}}`, true},
+		{`{"parameters": {"text": "a"}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 0}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 11}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1.5}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": 1, "count": 1}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1, "factor": null}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1, "values": null}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1, "values": 1}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1, "method": "slow"}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1, "unknown": 1}, "data": {"table": "t.csv"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1}, "data": {"table": "t.txt"}}`, false},
+		{`{"parameters": {"text": "a", "count": 1}}`, false},
+	}
+
+	for _, test := range tests {
+		var input toolspec.ToolInput
+		if err := json.Unmarshal([]byte(test.document), &input); err != nil {
+			t.Fatal(err)
+		}
+		var document interface{}
+		if err := json.Unmarshal([]byte(`{"tool": `+test.document+`}`), &document); err != nil {
+			t.Fatal(err)
+		}
+
+		accepted := accepts(inputSchema, document)
+		if accepted != test.accepted {
+			t.Errorf("the schema accepts %s: %t, want %t", test.document, accepted, test.accepted)
+		}
+		if !accepted {
+			continue
+		}
+		if hasErrors, errs := validate.ValidateInputs(testSpec, input); hasErrors {
+			for _, err := range errs {
+				t.Errorf("the schema accepts %s, but ValidateInputs does not: %s", test.document, err.Message)
+			}
+		}
+	}
+}
+
+func TestDateFormats(t *testing.T) {
+	for _, toolType := range []string{"date", "time", "datetime"} {
+		paramSchema, err := Parameter(toolspec.ParameterSpec{Name: "when", ToolType: toolType})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paramSchema.Format != "date-time" {
+			t.Errorf("%s parameters have the format %q, want date-time", toolType, paramSchema.Format)
+		}
+	}
+}
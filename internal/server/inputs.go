@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	goio "io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// DefaultMaxUploadSize limits the whole body of a request with inputs.
+const DefaultMaxUploadSize = 1 << 30
+
+// receiveInputs stores the submitted inputs.json in dir/in. The body is
+// either the inputs.json itself, or a multipart form with an inputs.json
+// part and any number of data files. Datasets referencing an uploaded file
+// by its name are pointed to the upload. Bodies larger than maxSize fail
+// with an *http.MaxBytesError.
+func receiveInputs(w http.ResponseWriter, r *http.Request, dir string, maxSize int64) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	inDir := filepath.Join(dir, "in")
+	if err := os.MkdirAll(inDir, 0755); err != nil {
+		return "", err
+	}
+	inputFile := filepath.Join(inDir, "inputs.json")
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		body, err := goio.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		return inputFile, os.WriteFile(inputFile, body, 0644)
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", err
+	}
+	var inputs []byte
+	uploads := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == goio.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if part.FormName() == "inputs.json" || part.FormName() == "inputs" {
+			inputs, err = goio.ReadAll(part)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		name := path.Base(part.FileName())
+		if name == "." || name == "/" || name == "inputs.json" {
+			return "", fmt.Errorf("invalid file name %q in upload", part.FileName())
+		}
+		file, err := os.Create(filepath.Join(inDir, name))
+		if err != nil {
+			return "", err
+		}
+		_, err = goio.Copy(file, part)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+		uploads[name] = filepath.Join(inDir, name)
+	}
+	if inputs == nil {
+		return "", fmt.Errorf("the upload has no inputs.json part")
+	}
+
+	inputs, err = pointToUploads(inputs, uploads)
+	if err != nil {
+		return "", err
+	}
+	return inputFile, os.WriteFile(inputFile, inputs, 0644)
+}
+
+// inputsError responds to a failed receiveInputs.
+func inputsError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the upload is larger than %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func pointToUploads(data []byte, uploads map[string]string) ([]byte, error) {
+	if len(uploads) == 0 {
+		return data, nil
+	}
+
+	inputs, err := toolspec.LoadInputs(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load input file: %w", err)
+	}
+	for toolname, tool := range inputs {
+		for name, dataPath := range tool.Datasets {
+			if upload, ok := uploads[path.Base(strings.ReplaceAll(dataPath, "\\", "/"))]; ok {
+				tool.Datasets[name] = upload
+			}
+		}
+		inputs[toolname] = tool
+	}
+	return json.MarshalIndent(inputs, "", "  ")
+}
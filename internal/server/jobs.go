@@ -0,0 +1,384 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/input"
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a single run of a tool. Its inputs and outputs live in their own
+// folder below the jobs folder of the server.
+type Job struct {
+	ID         string                 `json:"id"`
+	Tool       string                 `json:"tool"`
+	Status     JobStatus              `json:"status"`
+	Created    time.Time              `json:"created"`
+	Started    *time.Time             `json:"started,omitempty"`
+	Finished   *time.Time             `json:"finished,omitempty"`
+	ExitCode   *int                   `json:"exit_code,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Result     *input.ExecutionResult `json:"result,omitempty"`
+	dir        string
+	inputFile  string
+	input      toolspec.ToolInput
+	outputPath string
+}
+
+func (j Job) done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+func newJobID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	tool, err := s.loadTool(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	// the place in the queue is taken before the upload, so that a full
+	// queue is reported right away
+	select {
+	case s.queue <- struct{}{}:
+	default:
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("too many jobs are queued, try again later"))
+		return
+	}
+
+	id := newJobID()
+	dir := filepath.Join(s.options.JobsDir, id)
+	inputFile, err := receiveInputs(w, r, dir, s.options.MaxUploadSize)
+	if err != nil {
+		<-s.queue
+		os.RemoveAll(dir)
+		inputsError(w, err)
+		return
+	}
+
+	result, err := s.validateInputs(name, inputFile)
+	if err != nil {
+		<-s.queue
+		os.RemoveAll(dir)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if result.ErrorCount() > 0 || s.options.FailOnWarnings && result.WarningCount() > 0 {
+		<-s.queue
+		os.RemoveAll(dir)
+		writeJSON(w, http.StatusUnprocessableEntity, s.report(result, inputFile))
+		return
+	}
+
+	outputPath := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		<-s.queue
+		os.RemoveAll(dir)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	job := &Job{
+		ID:         id,
+		Tool:       name,
+		Status:     JobQueued,
+		Created:    time.Now(),
+		dir:        dir,
+		inputFile:  inputFile,
+		input:      result.ToolInput,
+		outputPath: outputPath,
+	}
+	s.mu.Lock()
+	s.jobs[id] = job
+	s.mu.Unlock()
+
+	go s.run(job, tool)
+
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, s.snapshot(job))
+}
+
+// snapshot copies the job, so that it can be encoded while it runs
+func (s *Server) snapshot(job *Job) Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *job
+}
+
+func (s *Server) update(job *Job, change func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(job)
+}
+
+func (s *Server) finish(job *Job, result *input.ExecutionResult, err error) {
+	s.update(job, func(job *Job) {
+		now := time.Now()
+		job.Finished = &now
+		job.Status = JobFailed
+		if err != nil {
+			job.Error = err.Error()
+			return
+		}
+		job.Result = result
+		job.ExitCode = &result.ExitCode
+		if len(result.OutputErrors) > 0 {
+			job.Error = result.OutputErrors[0].Message
+		} else if result.ExitCode == 0 {
+			job.Status = JobSucceeded
+		}
+	})
+}
+
+func (s *Server) run(job *Job, tool toolspec.ToolSpec) {
+	defer func() { <-s.queue }()
+	// wait for a free slot, as tools are usually not meant to run in parallel
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.update(job, func(job *Job) {
+		now := time.Now()
+		job.Started = &now
+		job.Status = JobRunning
+	})
+
+	command, err := input.ResolveCommand(tool)
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+	opts, err := s.options.ExecutionOptions(tool)
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+
+	before, err := io.SnapshotFolder(job.outputPath)
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+	stdoutFile, err := os.Create(filepath.Join(job.outputPath, "STDOUT"))
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+	defer stdoutFile.Close()
+	stderrFile, err := os.Create(filepath.Join(job.outputPath, "STDERR"))
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+	defer stderrFile.Close()
+
+	// the tool reads and writes the usual locations, which are mapped to
+	// the folder of the job
+	if err := s.sandbox.Enter(&opts, job.inputFile, job.outputPath); err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+
+	opts.ExportInputs(tool, job.input, job.inputFile)
+	opts.Stdout = stdoutFile
+	opts.Stderr = stderrFile
+	opts.Env = append(opts.Env,
+		"TAP_INPUT_FILE="+s.sandbox.InputFile,
+		"TAP_OUTPUT_FOLDER="+s.sandbox.OutputFolder,
+		"RUN_TOOL="+job.Tool,
+	)
+
+	result, err := input.ExecuteCommand(command, opts)
+	if err != nil {
+		s.finish(job, nil, err)
+		return
+	}
+	if result.ExitCode == 0 {
+		result.OutputErrors, err = validation.ValidateDeclaredOutputs(s.options.Files.Spec, job.Tool, job.outputPath, before)
+		if err != nil {
+			s.finish(job, nil, err)
+			return
+		}
+	}
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err == nil {
+		os.WriteFile(filepath.Join(job.outputPath, "_metadata.json"), jsonResult, 0644)
+	}
+	s.finish(job, &result, nil)
+}
+
+// removeExpiredJobs deletes finished jobs and their folders, once they are
+// older than the JobTTL.
+func (s *Server) removeExpiredJobs() {
+	ticker := time.NewTicker(min(s.options.JobTTL, time.Minute))
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		var expired []*Job
+		s.mu.Lock()
+		for id, job := range s.jobs {
+			if job.done() && now.Sub(*job.Finished) > s.options.JobTTL {
+				expired = append(expired, job)
+				delete(s.jobs, id)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, job := range expired {
+			os.RemoveAll(job.dir)
+		}
+	}
+}
+
+func (s *Server) lookupJob(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", r.PathValue("id")))
+	}
+	return job, ok
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.Before(jobs[j].Created) })
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(job))
+}
+
+// getLogs streams STDOUT or STDERR of a job. With follow=true, the
+// response stays open until the job finished.
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	stream := r.URL.Query().Get("stream")
+	if stream == "" {
+		stream = "stdout"
+	}
+	if stream != "stdout" && stream != "stderr" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("stream must be stdout or stderr"))
+		return
+	}
+	follow := r.URL.Query().Get("follow") == "true"
+	path := filepath.Join(job.outputPath, map[string]string{"stdout": "STDOUT", "stderr": "STDERR"}[stream])
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	var file *os.File
+	for {
+		// the state is checked before copying, so that the last copy of a
+		// finished job contains all of its output
+		done := s.snapshot(job).done()
+		if file == nil {
+			file, _ = os.Open(path)
+			if file != nil {
+				defer file.Close()
+			}
+		}
+		if file != nil {
+			goio.Copy(w, file)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if !follow || done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+type outputFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func (s *Server) listOutputs(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	files := make([]outputFile, 0)
+	err := filepath.WalkDir(job.outputPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(job.outputPath, path)
+		files = append(files, outputFile{Name: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) getOutput(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("file")
+	if !fs.ValidPath(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid file name %s", name))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(name)))
+	http.ServeFileFS(w, r, os.DirFS(job.outputPath), name)
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hydrocode-de/gotap/internal/input"
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/gotap/internal/metadata"
+	_ "github.com/hydrocode-de/gotap/internal/metadata/converters"
+	"github.com/hydrocode-de/gotap/internal/schema"
+	"github.com/hydrocode-de/gotap/internal/validation"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+)
+
+// Options configure the server. ExecutionOptions is called for every job,
+// so that the limits and timeouts of the tool are applied like in run.
+type Options struct {
+	Files          validation.Files
+	JobsDir        string
+	MaxJobs        int
+	FailOnWarnings bool
+	// DockerImage is passed to metadata formats, which run the tool
+	DockerImage      string
+	ExecutionOptions func(spec toolspec.ToolSpec) (input.ExecutionOptions, error)
+	// OutputFolder is where the tool writes its outputs, usually /out. Each
+	// job gets its own view of it and of Files.Input, see input.Sandbox
+	OutputFolder string
+	// JobTTL is how long finished jobs are kept. Zero keeps them forever
+	JobTTL time.Duration
+	// MaxQueuedJobs is the number of jobs waiting for a slot. Further jobs
+	// are rejected until one finished
+	MaxQueuedJobs int
+	// MaxUploadSize limits the body of requests with inputs, including the
+	// data files. Zero uses DefaultMaxUploadSize
+	MaxUploadSize int64
+}
+
+// Server exposes the tools of a tool.yml over HTTP and runs them as jobs.
+type Server struct {
+	options Options
+	slots   chan struct{}
+	// queue holds the jobs, which are queued or running
+	queue   chan struct{}
+	sandbox *input.Sandbox
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func New(options Options) (*Server, error) {
+	if options.MaxJobs <= 0 {
+		options.MaxJobs = 1
+	}
+	if options.MaxQueuedJobs < 0 {
+		options.MaxQueuedJobs = 0
+	}
+	if options.MaxUploadSize <= 0 {
+		options.MaxUploadSize = DefaultMaxUploadSize
+	}
+	if options.JobsDir == "" {
+		dir, err := os.MkdirTemp("", "gotap-jobs-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the jobs folder: %w", err)
+		}
+		options.JobsDir = dir
+	}
+	if err := os.MkdirAll(options.JobsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the jobs folder: %w", err)
+	}
+
+	sandbox, err := input.NewSandbox(options.Files.Input, options.OutputFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &Server{
+		options: options,
+		slots:   make(chan struct{}, options.MaxJobs),
+		queue:   make(chan struct{}, options.MaxJobs+options.MaxQueuedJobs),
+		sandbox: sandbox,
+		jobs:    make(map[string]*Job),
+	}
+	if options.JobTTL > 0 {
+		go srv.removeExpiredJobs()
+	}
+	return srv, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tools", s.listTools)
+	mux.HandleFunc("GET /tools/{name}", s.getTool)
+	mux.HandleFunc("GET /tools/{name}/metadata", s.getMetadata)
+	mux.HandleFunc("GET /tools/{name}/schema", s.getSchema)
+	mux.HandleFunc("POST /tools/{name}/validate", s.validate)
+	mux.HandleFunc("POST /tools/{name}/jobs", s.createJob)
+	mux.HandleFunc("GET /jobs", s.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	mux.HandleFunc("GET /jobs/{id}/logs", s.getLogs)
+	mux.HandleFunc("GET /jobs/{id}/outputs", s.listOutputs)
+	mux.HandleFunc("GET /jobs/{id}/outputs/{file...}", s.getOutput)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// loadTool reads the tool from tool.yml on every request, so that changes
+// to the spec do not need a restart of the server.
+func (s *Server) loadTool(name string) (toolspec.ToolSpec, error) {
+	spec, err := io.ReadSpecFile(s.options.Files.Spec)
+	if err != nil {
+		return toolspec.ToolSpec{}, err
+	}
+	tool, err := spec.GetTool(name)
+	if err != nil {
+		return toolspec.ToolSpec{}, err
+	}
+	if citation, err := io.ReadCitationFile(s.options.Files.Citation); err == nil {
+		tool.Citation = citation
+	}
+	return tool, nil
+}
+
+type toolSummary struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+func (s *Server) listTools(w http.ResponseWriter, r *http.Request) {
+	spec, err := io.ReadSpecFile(s.options.Files.Spec)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tools := make([]toolSummary, 0, len(spec.Tools))
+	for name, tool := range spec.Tools {
+		tools = append(tools, toolSummary{Name: name, Title: tool.Title, Description: tool.Description})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	writeJSON(w, http.StatusOK, tools)
+}
+
+func (s *Server) getTool(w http.ResponseWriter, r *http.Request) {
+	tool, err := s.loadTool(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tool)
+}
+
+// getMetadata serves schema.org metadata by default. Other formats and
+// serializations can be requested like with generate.
+func (s *Server) getMetadata(w http.ResponseWriter, r *http.Request) {
+	tool, err := s.loadTool(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "schema.org"
+	}
+	format, err := metadata.Lookup(name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	converter := format.New()
+	converter.Ingest(tool)
+	if ingester, ok := converter.(metadata.LicenseIngester); ok {
+		if license, err := io.ReadLicenseFile(s.options.Files.License); err == nil {
+			ingester.IngestLicense(license)
+		}
+	}
+	if ingester, ok := converter.(metadata.ImageIngester); ok {
+		ingester.IngestImage(s.options.DockerImage)
+	}
+	extras, err := io.ReadToolExtras(s.options.Files.Spec, tool.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if ingester, ok := converter.(metadata.VersionIngester); ok {
+		ingester.IngestVersion(extras.Version)
+	}
+	if ingester, ok := converter.(metadata.OutputsIngester); ok {
+		ingester.IngestOutputs(extras.Outputs)
+	}
+	if err := converter.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	data, err := converter.Serialize(r.URL.Query().Get("serialization"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Write(data)
+}
+
+func (s *Server) getSchema(w http.ResponseWriter, r *http.Request) {
+	tool, err := s.loadTool(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	inputSchema, err := schema.Inputs(tool)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(inputSchema)
+}
+
+// validate checks a submitted inputs.json without running the tool.
+func (s *Server) validate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := s.loadTool(name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	dir, err := os.MkdirTemp(s.options.JobsDir, "validate-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	inputFile, err := receiveInputs(w, r, dir, s.options.MaxUploadSize)
+	if err != nil {
+		inputsError(w, err)
+		return
+	}
+
+	result, err := s.validateInputs(name, inputFile)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.report(result, inputFile))
+}
+
+func (s *Server) validateInputs(name string, inputFile string) (validation.ValidationResult, error) {
+	files := s.options.Files
+	files.Input = inputFile
+	return validation.ValidateFiles(files, []string{name})
+}
+
+func (s *Server) report(result validation.ValidationResult, inputFile string) io.ValidationReport {
+	return io.ValidationReport{
+		Tool:         result.ToolSpec.Name,
+		Status:       result.Status(),
+		SpecFile:     s.options.Files.Spec,
+		InputFile:    inputFile,
+		ErrorCount:   result.ErrorCount(),
+		WarningCount: result.WarningCount(),
+		Errors:       result.Errors,
+		Warnings:     result.Warnings,
+	}
+}
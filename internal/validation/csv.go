@@ -0,0 +1,334 @@
+package validation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	goio "io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+const (
+	InvalidEncoding validate.ErrorType = "invalid-encoding"
+	MalformedCSV    validate.ErrorType = "malformed-csv"
+	ColumnCount     validate.ErrorType = "column-count"
+	MissingColumn   validate.ErrorType = "missing-column"
+)
+
+// maxCSVErrors stops the row checks of a file, so that a broken file does
+// not flood the result
+const maxCSVErrors = 10
+
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// ValidateCSVFiles parses all CSV datasets of the tool. The delimiter is
+// taken from tool.yml or guessed from the header. All rows need the same
+// number of columns as the header and the file must be valid UTF-8. If
+// columns are declared in tool.yml, they are checked as well.
+func ValidateCSVFiles(spec toolspec.ToolSpec, input toolspec.ToolInput, inputFile string, extras io.ToolExtras) []*validate.ValidationError {
+	errs := make([]*validate.ValidationError, 0)
+
+	names := make([]string, 0, len(input.Datasets))
+	for name, dataSpec := range spec.Data {
+		if _, ok := input.Datasets[name]; ok && isCSV(dataSpec) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		errs = append(errs, columnTypeErrors(name, extras.Data[name])...)
+
+		path := io.ResolveDataPath(input.Datasets[name], inputFile)
+
+		// missing or empty files are reported by ValidateDataFiles
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() == 0 {
+			continue
+		}
+
+		errs = append(errs, validateCSVFile(name, path, extras.Data[name])...)
+	}
+
+	return errs
+}
+
+func isCSV(dataSpec toolspec.DataSpec) bool {
+	for _, ext := range dataSpec.Extensions {
+		if strings.ToLower(strings.TrimPrefix(ext, ".")) == "csv" {
+			return true
+		}
+	}
+	return false
+}
+
+// columnTypes are the types a declared column can have. string and an
+// empty type accept any value.
+var columnTypes = []string{"string", "integer", "float", "boolean", "datetime", "date", "time"}
+
+// columnTypeErrors reports declared columns of unknown type as spec error,
+// as their values could not be checked otherwise.
+func columnTypeErrors(name string, extras io.DataExtras) []*validate.ValidationError {
+	errs := make([]*validate.ValidationError, 0)
+	for _, column := range extras.Columns {
+		if column.Type == "" || slices.Contains(columnTypes, column.Type) {
+			continue
+		}
+		errs = append(errs, &validate.ValidationError{
+			Field:    validate.Data,
+			Name:     name,
+			Type:     InvalidSpec,
+			Expected: fmt.Sprintf("one of %s", strings.Join(columnTypes, ", ")),
+			Actual:   column.Type,
+			Message:  fmt.Sprintf("column %s of data %s has the unknown type %s in tool.yml", column.Name, name, column.Type),
+		})
+	}
+	return errs
+}
+
+// errInvalidEncoding stops the CSV reader at the first line with invalid
+// UTF-8, which encoding/csv would accept otherwise.
+var errInvalidEncoding = errors.New("invalid UTF-8")
+
+// utf8Reader checks the encoding line by line, while the file is parsed.
+type utf8Reader struct {
+	reader      *bufio.Reader
+	line        int
+	pending     []byte
+	err         error
+	invalidLine int
+}
+
+func (r *utf8Reader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.pending, r.err = r.reader.ReadBytes('\n')
+		r.line++
+		if !utf8.Valid(r.pending) {
+			r.pending = nil
+			r.invalidLine = r.line
+			r.err = errInvalidEncoding
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func validateCSVFile(name string, path string, extras io.DataExtras) []*validate.ValidationError {
+	errs := make([]*validate.ValidationError, 0)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return append(errs, notReadable(name, path, err))
+	}
+	defer file.Close()
+
+	// the header is only peeked to guess the delimiter, the file is parsed
+	// in a single pass
+	reader := bufio.NewReaderSize(file, 64*1024)
+	header, _ := reader.Peek(reader.Size())
+	if end := bytes.IndexByte(header, '\n'); end >= 0 {
+		header = header[:end]
+	}
+
+	delimiter, err := csvDelimiter(header, extras.Delimiter)
+	if err != nil {
+		return append(errs, &validate.ValidationError{
+			Field:    validate.Data,
+			Name:     name,
+			Type:     InvalidSpec,
+			Expected: "a single character delimiter",
+			Actual:   extras.Delimiter,
+			Message:  fmt.Sprintf("the delimiter of data file %s is invalid: %s", name, err),
+		})
+	}
+
+	encoding := &utf8Reader{reader: reader}
+	csvReader := csv.NewReader(encoding)
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+
+	columns, err := csvReader.Read()
+	if err != nil {
+		return append(errs, csvError(name, err, encoding))
+	}
+	columns[0] = strings.TrimPrefix(columns[0], "\ufeff")
+
+	// map the declared columns to their position in the header
+	declared := make(map[int]io.ColumnSpec)
+	for _, column := range extras.Columns {
+		index := slices.Index(columns, column.Name)
+		if index < 0 {
+			errs = append(errs, &validate.ValidationError{
+				Field:    validate.Data,
+				Name:     name,
+				Type:     MissingColumn,
+				Expected: column.Name,
+				Actual:   strings.Join(columns, string(delimiter)),
+				Message:  fmt.Sprintf("data file %s has no column %s", name, column.Name),
+			})
+			continue
+		}
+		declared[index] = column
+	}
+	// check the columns in file order, so that the errors are stable
+	indices := make([]int, 0, len(declared))
+	for index := range declared {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	for len(errs) < maxCSVErrors {
+		record, err := csvReader.Read()
+		if errors.Is(err, goio.EOF) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, csvError(name, err, encoding))
+			break
+		}
+		// errors name the line in the file, as the header and quoted line
+		// breaks make the row number differ from it
+		line, _ := csvReader.FieldPos(0)
+
+		if len(record) != len(columns) {
+			errs = append(errs, &validate.ValidationError{
+				Field:    validate.Data,
+				Name:     name,
+				Type:     ColumnCount,
+				Expected: fmt.Sprintf("%d columns", len(columns)),
+				Actual:   fmt.Sprintf("%d columns in line %d", len(record), line),
+				Message:  fmt.Sprintf("line %d of data file %s has %d columns, but the header has %d", line, name, len(record), len(columns)),
+			})
+			continue
+		}
+
+		for _, index := range indices {
+			column := declared[index]
+			if err := checkCSVValue(record[index], column); err != nil {
+				errs = append(errs, &validate.ValidationError{
+					Field:    validate.Data,
+					Name:     name,
+					Type:     validate.WrongType,
+					Expected: column.Type,
+					Actual:   fmt.Sprintf("%q in line %d, column %s", record[index], line, column.Name),
+					Message:  fmt.Sprintf("line %d, column %s of data file %s: %s", line, column.Name, name, err),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// csvError reports encoding problems found while parsing as such, and
+// everything else as malformed CSV.
+func csvError(name string, err error, encoding *utf8Reader) *validate.ValidationError {
+	if errors.Is(err, errInvalidEncoding) {
+		return &validate.ValidationError{
+			Field:    validate.Data,
+			Name:     name,
+			Type:     InvalidEncoding,
+			Expected: "UTF-8",
+			Actual:   fmt.Sprintf("invalid byte sequence in line %d", encoding.invalidLine),
+			Message:  fmt.Sprintf("data file %s is not valid UTF-8 in line %d", name, encoding.invalidLine),
+		}
+	}
+	return malformedCSV(name, err)
+}
+
+// csvDelimiter uses the declared delimiter, or picks the candidate found
+// most often in the header line.
+func csvDelimiter(header []byte, declared string) (rune, error) {
+	if declared != "" {
+		if declared == `\t` {
+			return '\t', nil
+		}
+		delimiter, size := utf8.DecodeRuneInString(declared)
+		if size != len(declared) {
+			return ',', fmt.Errorf("%q is not a single character", declared)
+		}
+		return delimiter, nil
+	}
+
+	delimiter := ','
+	count := 0
+	for _, candidate := range csvDelimiters {
+		if n := bytes.Count(header, []byte(string(candidate))); n > count {
+			delimiter = candidate
+			count = n
+		}
+	}
+	return delimiter, nil
+}
+
+// dateTimeLayouts are accepted for all date and time columns.
+var dateTimeLayouts = []string{
+	time.RFC3339,
+	time.DateTime,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+	time.TimeOnly,
+}
+
+func checkCSVValue(value string, column io.ColumnSpec) error {
+	if value == "" {
+		if column.Optional {
+			return nil
+		}
+		return fmt.Errorf("the value is empty, but the column is not optional")
+	}
+
+	var err error
+	switch column.Type {
+	case "integer":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	case "datetime", "date", "time":
+		for _, layout := range dateTimeLayouts {
+			if _, err = time.Parse(layout, value); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("expected type %s", column.Type)
+	}
+	return nil
+}
+
+func malformedCSV(name string, err error) *validate.ValidationError {
+	actual := err.Error()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		actual = fmt.Sprintf("%s in line %d, column %d", parseErr.Err, parseErr.Line, parseErr.Column)
+	}
+
+	return &validate.ValidationError{
+		Field:    validate.Data,
+		Name:     name,
+		Type:     MalformedCSV,
+		Expected: "a parsable CSV file",
+		Actual:   actual,
+		Message:  fmt.Sprintf("data file %s could not be parsed as CSV: %s", name, actual),
+	}
+}
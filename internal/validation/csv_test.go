@@ -0,0 +1,183 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		header   string
+		declared string
+		want     rune
+		wantErr  bool
+	}{
+		{header: "a,b,c", want: ','},
+		{header: "a;b;c", want: ';'},
+		{header: "a\tb\tc", want: '\t'},
+		{header: "a|b|c", want: '|'},
+		{header: "a;b,c;d", want: ';'},
+		{header: "single", want: ','},
+		{header: "a;b;c", declared: ",", want: ','},
+		{header: "a b", declared: `\t`, want: '\t'},
+		{header: "a b", declared: "ab", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := csvDelimiter([]byte(test.header), test.declared)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("csvDelimiter(%q, %q) = %q, want an error", test.header, test.declared, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("csvDelimiter(%q, %q) = %q, %v, want %q", test.header, test.declared, got, err, test.want)
+		}
+	}
+}
+
+func TestValidateCSVFile(t *testing.T) {
+	columns := io.DataExtras{Columns: []io.ColumnSpec{
+		{Name: "id", Type: "integer"},
+		{Name: "value", Type: "float", Optional: true},
+		{Name: "time", Type: "datetime"},
+	}}
+
+	tests := []struct {
+		name    string
+		content string
+		extras  io.DataExtras
+		want    []validate.ErrorType
+		message string
+	}{
+		{
+			name:    "valid",
+			content: "id,value,time\n1,0.5,2024-01-01T12:00:00Z\n2,,2024-01-01 12:00:00\n3,1e3,2024-01-01\n",
+			extras:  columns,
+		},
+		{
+			name:    "semicolons and a byte order mark",
+			content: "\ufeffid;value;time\n1;0.5;2024-01-01\n",
+			extras:  columns,
+		},
+		{
+			name:    "wrong types name the line",
+			content: "id,value,time\n1,x,2024-01-01\n",
+			extras:  columns,
+			want:    []validate.ErrorType{validate.WrongType},
+			message: "line 2, column value",
+		},
+		{
+			name:    "quoted line breaks count as lines",
+			content: "id,value,time\n\"1\",\"0\n5\",2024-01-01\nx,1,2024-01-01\n",
+			extras:  io.DataExtras{Columns: []io.ColumnSpec{{Name: "id", Type: "integer"}}},
+			want:    []validate.ErrorType{validate.WrongType},
+			message: "line 4, column id",
+		},
+		{
+			name:    "missing column",
+			content: "id,time\n1,2024-01-01\n",
+			extras:  columns,
+			want:    []validate.ErrorType{MissingColumn},
+		},
+		{
+			name:    "column count",
+			content: "a,b\n1,2\n1,2,3\n",
+			want:    []validate.ErrorType{ColumnCount},
+			message: "line 3",
+		},
+		{
+			name:    "invalid utf-8",
+			content: "a,b\n1,2\n\xff\xfe,3\n",
+			want:    []validate.ErrorType{InvalidEncoding},
+			message: "line 3",
+		},
+		{
+			name:    "latin-1 in the header",
+			content: "gr\xf6\xdfe,b\n1,2\n",
+			want:    []validate.ErrorType{InvalidEncoding},
+			message: "line 1",
+		},
+		{
+			name:    "malformed quotes",
+			content: "a,b\n\"1,2\n",
+			want:    []validate.ErrorType{MalformedCSV},
+		},
+		{
+			name:    "invalid delimiter",
+			content: "a,b\n1,2\n",
+			extras:  io.DataExtras{Delimiter: ";;"},
+			want:    []validate.ErrorType{InvalidSpec},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validateCSVFile("foo_csv", writeCSV(t, test.content), test.extras)
+			if len(errs) != len(test.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs), len(test.want), messages(errs))
+			}
+			for i, err := range errs {
+				if err.Type != test.want[i] {
+					t.Errorf("error %d has type %s, want %s: %s", i, err.Type, test.want[i], err.Message)
+				}
+			}
+			if test.message != "" && !strings.Contains(errs[0].Message, test.message) {
+				t.Errorf("message %q does not contain %q", errs[0].Message, test.message)
+			}
+		})
+	}
+}
+
+func TestValidateCSVFileStopsAtMaxErrors(t *testing.T) {
+	content := "id\n" + strings.Repeat("x\n", 3*maxCSVErrors)
+	extras := io.DataExtras{Columns: []io.ColumnSpec{{Name: "id", Type: "integer"}}}
+
+	errs := validateCSVFile("foo_csv", writeCSV(t, content), extras)
+	if len(errs) != maxCSVErrors {
+		t.Fatalf("got %d errors, want %d", len(errs), maxCSVErrors)
+	}
+	if !strings.Contains(errs[len(errs)-1].Message, "line 11,") {
+		t.Errorf("the last error should be in line 11: %s", errs[len(errs)-1].Message)
+	}
+}
+
+func TestColumnTypeErrors(t *testing.T) {
+	extras := io.DataExtras{Columns: []io.ColumnSpec{
+		{Name: "id", Type: "interger"},
+		{Name: "name"},
+		{Name: "label", Type: "string"},
+		{Name: "day", Type: "date"},
+	}}
+
+	errs := columnTypeErrors("foo_csv", extras)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), messages(errs))
+	}
+	if errs[0].Type != InvalidSpec || errs[0].Actual != "interger" {
+		t.Errorf("unexpected error %s: %s", errs[0].Type, errs[0].Message)
+	}
+}
+
+func messages(errs []*validate.ValidationError) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	toolspec "github.com/hydrocode-de/tool-spec-go"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+const (
+	NotFound    validate.ErrorType = "not-found"
+	NotReadable validate.ErrorType = "not-readable"
+	IsDirectory validate.ErrorType = "is-directory"
+	EmptyFile   validate.ErrorType = "empty-file"
+)
+
+// ValidateDataFiles checks that every dataset declared for the tool exists
+// on disk and can be read. Datasets with an extension are expected to be
+// non-empty files. Missing entries and wrong extensions in inputs.json are
+// already reported by validate.ValidateData.
+func ValidateDataFiles(spec toolspec.ToolSpec, input toolspec.ToolInput, inputFile string) []*validate.ValidationError {
+	errs := make([]*validate.ValidationError, 0)
+
+	names := make([]string, 0, len(input.Datasets))
+	for name := range input.Datasets {
+		if _, ok := spec.Data[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dataPath := input.Datasets[name]
+		path := io.ResolveDataPath(dataPath, inputFile)
+		expectsFile := len(spec.Data[name].Extensions) > 0
+
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, &validate.ValidationError{
+				Field:    validate.Data,
+				Name:     name,
+				Type:     NotFound,
+				Expected: "an existing file",
+				Actual:   dataPath,
+				Message:  fmt.Sprintf("data file %s does not exist at %s", name, dataPath),
+			})
+			continue
+		}
+		if err != nil {
+			errs = append(errs, notReadable(name, dataPath, err))
+			continue
+		}
+
+		if info.IsDir() {
+			if expectsFile {
+				errs = append(errs, &validate.ValidationError{
+					Field:    validate.Data,
+					Name:     name,
+					Type:     IsDirectory,
+					Expected: "a file",
+					Actual:   "a directory",
+					Message:  fmt.Sprintf("data %s is a directory, but a file is expected", name),
+				})
+			} else if _, err := os.ReadDir(path); err != nil {
+				errs = append(errs, notReadable(name, dataPath, err))
+			}
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			errs = append(errs, notReadable(name, dataPath, err))
+			continue
+		}
+		file.Close()
+
+		if info.Size() == 0 {
+			errs = append(errs, &validate.ValidationError{
+				Field:    validate.Data,
+				Name:     name,
+				Type:     EmptyFile,
+				Expected: "a non-empty file",
+				Actual:   "0 bytes",
+				Message:  fmt.Sprintf("data file %s at %s is empty", name, dataPath),
+			})
+		}
+	}
+
+	return errs
+}
+
+func notReadable(name string, path string, err error) *validate.ValidationError {
+	return &validate.ValidationError{
+		Field:    validate.Data,
+		Name:     name,
+		Type:     NotReadable,
+		Expected: "a readable file",
+		Actual:   err.Error(),
+		Message:  fmt.Sprintf("data file %s at %s cannot be read", name, path),
+	}
+}
@@ -0,0 +1,136 @@
+package validation
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+const (
+	Outputs         validate.AllowedField = "outputs"
+	MissingOutput   validate.ErrorType    = "missing-output"
+	OutputExtension validate.ErrorType    = "wrong-extension"
+)
+
+// ValidateOutputs checks the files written by a run against the declared
+// outputs. The files are relative to the output folder and use slashes.
+// An output is found by its glob, or by its name with any extension at the
+// top level of the folder. It is missing, if nothing is found and it is not
+// optional. Found files need to have one of the declared extensions.
+func ValidateOutputs(outputs map[string]io.OutputSpec, files []string) []*validate.ValidationError {
+	errs := make([]*validate.ValidationError, 0)
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := outputs[name]
+		extensions := make([]string, 0, len(spec.Extension))
+		for _, ext := range spec.Extension {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			extensions = append(extensions, ext)
+		}
+
+		if spec.Glob != "" {
+			if _, err := path.Match(spec.Glob, ""); err != nil {
+				errs = append(errs, &validate.ValidationError{
+					Field:    Outputs,
+					Name:     name,
+					Type:     InvalidSpec,
+					Expected: "a valid glob",
+					Actual:   spec.Glob,
+					Message:  fmt.Sprintf("output %s has the invalid glob %s in tool.yml", name, spec.Glob),
+				})
+				continue
+			}
+		}
+
+		var matches []string
+		for _, file := range files {
+			if outputMatches(name, spec.Glob, file) {
+				matches = append(matches, file)
+			}
+		}
+
+		if len(matches) == 0 {
+			if !spec.Optional {
+				expected := name
+				if spec.Glob != "" {
+					expected = spec.Glob
+				} else if len(extensions) > 0 {
+					expected = name + extensions[0]
+				}
+				errs = append(errs, &validate.ValidationError{
+					Field:    Outputs,
+					Name:     name,
+					Type:     MissingOutput,
+					Expected: expected,
+					Actual:   "None",
+					Message:  fmt.Sprintf("the tool did not write the required output %s", name),
+				})
+			}
+			continue
+		}
+
+		if len(extensions) == 0 {
+			continue
+		}
+		for _, file := range matches {
+			ext := strings.ToLower(path.Ext(file))
+			if !slices.Contains(extensions, ext) {
+				errs = append(errs, &validate.ValidationError{
+					Field:    Outputs,
+					Name:     name,
+					Type:     OutputExtension,
+					Expected: fmt.Sprintf("one of %v", extensions),
+					Actual:   file,
+					Message:  fmt.Sprintf("output %s has an invalid extension, expected one of %v", name, extensions),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// ValidateDeclaredOutputs reads the outputs declared for a tool in tool.yml
+// and validates the files written to the output folder since the snapshot
+// against them. Files of earlier runs do not count as outputs.
+func ValidateDeclaredOutputs(specFile string, toolname string, outputFolder string, before io.FolderSnapshot) ([]*validate.ValidationError, error) {
+	extras, err := io.ReadToolExtras(specFile, toolname)
+	if err != nil {
+		return nil, err
+	}
+	if len(extras.Outputs) == 0 {
+		return nil, nil
+	}
+	written, err := before.Changed(outputFolder)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateOutputs(extras.Outputs, written), nil
+}
+
+// outputMatches finds outputs by their glob, or by their name in the top
+// level of the output folder.
+func outputMatches(name string, glob string, file string) bool {
+	if glob != "" {
+		matched, _ := path.Match(glob, file)
+		return matched
+	}
+	if strings.Contains(file, "/") {
+		return false
+	}
+	return file == name || strings.TrimSuffix(file, path.Ext(file)) == name
+}
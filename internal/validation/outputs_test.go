@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hydrocode-de/gotap/internal/io"
+	"github.com/hydrocode-de/tool-spec-go/validate"
+)
+
+func TestValidateOutputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		output io.OutputSpec
+		files  []string
+		want   []validate.ErrorType
+	}{
+		{name: "result", files: []string{"result.csv"}},
+		{name: "result", files: []string{"result"}},
+		{name: "result", output: io.OutputSpec{Extension: io.StringList{"csv"}}, files: []string{"result.CSV"}},
+		{name: "result", output: io.OutputSpec{Extension: io.StringList{"csv"}}, files: []string{"result.txt"}, want: []validate.ErrorType{OutputExtension}},
+		{name: "result", files: []string{"STDOUT"}, want: []validate.ErrorType{MissingOutput}},
+		{name: "result", files: []string{"nested/result.csv"}, want: []validate.ErrorType{MissingOutput}},
+		{name: "result", output: io.OutputSpec{Optional: true}},
+		{name: "plots", output: io.OutputSpec{Glob: "plots/*.png"}, files: []string{"plots/a.png", "plots/b.png"}},
+		{name: "plots", output: io.OutputSpec{Glob: "plots/*", Extension: io.StringList{".png"}}, files: []string{"plots/a.png", "plots/b.svg"}, want: []validate.ErrorType{OutputExtension}},
+		{name: "plots", output: io.OutputSpec{Glob: "plots/*.png"}, files: []string{"a.png"}, want: []validate.ErrorType{MissingOutput}},
+		{name: "plots", output: io.OutputSpec{Glob: "plots/[a.png"}, files: []string{"plots/a.png"}, want: []validate.ErrorType{InvalidSpec}},
+	}
+
+	for _, test := range tests {
+		errs := ValidateOutputs(map[string]io.OutputSpec{test.name: test.output}, test.files)
+		types := make([]validate.ErrorType, 0, len(errs))
+		for _, err := range errs {
+			types = append(types, err.Type)
+		}
+		if !slices.Equal(types, test.want) && !(len(types) == 0 && len(test.want) == 0) {
+			t.Errorf("output %s %+v with files %v: got %v, want %v", test.name, test.output, test.files, messages(errs), test.want)
+		}
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"github.com/hydrocode-de/tool-spec-go/validate"
 )
 
+// InvalidSpec marks errors in tool.yml, which were found while validating
+// the inputs or outputs.
+const InvalidSpec validate.ErrorType = "invalid-spec"
+
 type ValidationResult struct {
 	ToolSpec  toolspec.ToolSpec
 	ToolInput toolspec.ToolInput
@@ -24,12 +28,47 @@ func (r *ValidationResult) WarningCount() int {
 	return len(r.Warnings)
 }
 
-func LoadAndValidateSpec(args []string) (ValidationResult, error) {
+// Status summarizes the result as OK, WARN or FAIL.
+func (r *ValidationResult) Status() string {
+	if r.ErrorCount() > 0 {
+		return "FAIL"
+	}
+	if r.WarningCount() > 0 {
+		return "WARN"
+	}
+	return "OK"
+}
+
+// Files are the paths of the files, which make up a tool and its inputs.
+type Files struct {
+	Spec     string
+	Input    string
+	Citation string
+	License  string
+}
+
+// ConfiguredFiles returns the files set by flags, env or defaults.
+func ConfiguredFiles() Files {
 	v := config.GetViper()
-	specFile := v.GetString("spec_file")
-	inputFile := v.GetString("input_file")
-	citationFile := v.GetString("citation_file")
-	licenseFile := v.GetString("license_file")
+	return Files{
+		Spec:     v.GetString("spec_file"),
+		Input:    v.GetString("input_file"),
+		Citation: v.GetString("citation_file"),
+		License:  v.GetString("license_file"),
+	}
+}
+
+func LoadAndValidateSpec(args []string) (ValidationResult, error) {
+	return ValidateFiles(ConfiguredFiles(), args)
+}
+
+// ValidateFiles loads and validates the given files, like
+// LoadAndValidateSpec does for the configured ones.
+func ValidateFiles(files Files, args []string) (ValidationResult, error) {
+	specFile := files.Spec
+	inputFile := files.Input
+	citationFile := files.Citation
+	licenseFile := files.License
 
 	warnings := make([]*validate.ValidationError, 0)
 	errors := make([]*validate.ValidationError, 0)
@@ -74,11 +113,16 @@ func LoadAndValidateSpec(args []string) (ValidationResult, error) {
 	}
 
 	hasErrors, errs := validate.ValidateInputs(toolSpec, toolInput)
-	if !hasErrors {
-		for _, err := range errs {
-			errors = append(errors, err)
-		}
+	if hasErrors {
+		errors = append(errors, errs...)
+	}
+	errors = append(errors, ValidateDataFiles(toolSpec, toolInput, inputFile)...)
+
+	extras, err := io.ReadToolExtras(specFile, toolname)
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("critical. failed to read tool.yml file: %w", err)
 	}
+	errors = append(errors, ValidateCSVFiles(toolSpec, toolInput, inputFile, extras)...)
 
 	_, err = io.ReadLicenseFile(licenseFile)
 	if err != nil {